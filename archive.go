@@ -0,0 +1,314 @@
+package dlutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	DefaultMaxArchiveSize    = 1 << 30
+	DefaultMaxArchiveEntries = 100000
+)
+
+var (
+	ErrUnknownArchive = errors.New("unknown archive format")
+	ErrUnsafePath     = errors.New("unsafe path in archive")
+	ErrArchiveLimit   = errors.New("archive limit exceeded")
+	SkipEntry         = errors.New("skip this entry")
+)
+
+type ArchiveEntry struct {
+	Name    string
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+}
+
+func (e ArchiveEntry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+func WithArchiveEntryFunc(fn func(entry ArchiveEntry) error) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.ArchiveEntryFunc = fn
+	}
+}
+
+func WithArchiveLimits(maxSize int64, maxEntries int) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.MaxArchiveSize = maxSize
+		do.MaxArchiveEntries = maxEntries
+	}
+}
+
+type archiveFormat int
+
+const (
+	formatUnknown archiveFormat = iota
+	formatTar
+	formatTarGz
+	formatTarZst
+	formatZip
+)
+
+func DownloadArchive(url, destDir string, o ...DownloadOption) error {
+	opts := newDownloadOptions(o)
+	body, resp, err := doDownload(url, &opts)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	br := bufio.NewReader(body)
+	format := detectArchiveFormat(url, resp, br)
+	x := &extractor{
+		destDir:    destDir,
+		entryFunc:  opts.ArchiveEntryFunc,
+		maxSize:    opts.MaxArchiveSize,
+		maxEntries: opts.MaxArchiveEntries,
+	}
+	switch format {
+	case formatTar:
+		return x.extractTar(br)
+	case formatTarGz:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return x.extractTar(zr)
+	case formatTarZst:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return x.extractTar(zr)
+	case formatZip:
+		return x.extractZip(br)
+	default:
+		return ErrUnknownArchive
+	}
+}
+
+func detectArchiveFormat(url string, resp *http.Response, br *bufio.Reader) archiveFormat {
+	var filename string
+	if u, err := neturl.Parse(url); err == nil {
+		filename = strings.ToLower(path.Base(u.Path))
+	}
+	if resp != nil {
+		if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && len(params["filename"]) > 0 {
+			filename = strings.ToLower(params["filename"])
+		}
+		switch contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); contentType {
+		case "application/zip", "application/x-zip-compressed":
+			return formatZip
+		case "application/x-tar":
+			return formatTar
+		case "application/zstd":
+			return formatTarZst
+		case "application/gzip", "application/x-gzip", "application/x-compressed-tar":
+			return formatTarGz
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(filename, ".zip"):
+		return formatZip
+	case strings.HasSuffix(filename, ".tar"):
+		return formatTar
+	case strings.HasSuffix(filename, ".tar.gz"), strings.HasSuffix(filename, ".tgz"):
+		return formatTarGz
+	case strings.HasSuffix(filename, ".tar.zst"), strings.HasSuffix(filename, ".tzst"):
+		return formatTarZst
+	}
+
+	magic, _ := br.Peek(262)
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		return formatZip
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return formatTarGz
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return formatTarZst
+	case len(magic) >= 262 && string(magic[257:262]) == "ustar":
+		return formatTar
+	}
+	return formatUnknown
+}
+
+type extractor struct {
+	destDir    string
+	entryFunc  func(entry ArchiveEntry) error
+	maxSize    int64
+	maxEntries int
+	size       int64
+	entries    int
+}
+
+func (x *extractor) extractTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// links are skipped to avoid writing outside of destDir through them
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeDir {
+			continue
+		}
+		entry := ArchiveEntry{
+			Name:    hdr.Name,
+			Size:    hdr.Size,
+			Mode:    hdr.FileInfo().Mode(),
+			ModTime: hdr.ModTime,
+		}
+		if err := x.extractEntry(entry, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extractor) extractZip(r io.Reader) error {
+	tmp, err := os.CreateTemp("", "dlutil-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		mode := f.Mode()
+		if !mode.IsRegular() && !mode.IsDir() {
+			continue
+		}
+		entry := ArchiveEntry{
+			Name:    f.Name,
+			Size:    int64(f.UncompressedSize64),
+			Mode:    mode,
+			ModTime: f.Modified,
+		}
+		if err := x.extractZipFile(entry, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) extractZipFile(entry ArchiveEntry, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return x.extractEntry(entry, rc)
+}
+
+func (x *extractor) extractEntry(entry ArchiveEntry, r io.Reader) error {
+	if x.entries++; x.maxEntries > 0 && x.entries > x.maxEntries {
+		return ErrArchiveLimit
+	}
+	target, err := safeJoin(x.destDir, entry.Name)
+	if err != nil {
+		return err
+	}
+	if x.entryFunc != nil {
+		if err := x.entryFunc(entry); err == SkipEntry {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if err := checkNoSymlinks(x.destDir, target); err != nil {
+		return err
+	}
+	if entry.IsDir() {
+		return os.MkdirAll(target, 0o755)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, entry.Mode.Perm()|0o600)
+	if err != nil {
+		return err
+	}
+	if x.maxSize > 0 {
+		r = io.LimitReader(r, x.maxSize-x.size+1)
+	}
+	n, err := io.Copy(f, r)
+	if x.size += n; err == nil && x.maxSize > 0 && x.size > x.maxSize {
+		err = ErrArchiveLimit
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func checkNoSymlinks(dir, target string) error {
+	rel, err := filepath.Rel(filepath.Clean(dir), target)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	current := filepath.Clean(dir)
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		fi, err := os.Lstat(current)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			return ErrUnsafePath
+		}
+	}
+	return nil
+}
+
+func safeJoin(dir, name string) (string, error) {
+	dir = filepath.Clean(dir)
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", ErrUnsafePath
+	}
+	target := filepath.Join(dir, name)
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrUnsafePath
+	}
+	return target, nil
+}
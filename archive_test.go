@@ -0,0 +1,139 @@
+package dlutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testArchiveFile struct {
+	name    string
+	content string
+}
+
+func newTarArchive(t *testing.T, files ...testArchiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.content)), Typeflag: tar.TypeReg}
+		if f.name[len(f.name)-1] == '/' {
+			hdr.Mode, hdr.Size, hdr.Typeflag = 0o755, 0, tar.TypeDir
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(f.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newZipArchive(t *testing.T, files ...testArchiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func serveArchive(t *testing.T, contentType string, archive []byte) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(archive)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestDownloadArchive(t *testing.T) {
+	files := []testArchiveFile{{"dir/", ""}, {"dir/a.txt", "hello"}, {"b.txt", "world"}}
+	archives := map[string]string{
+		"tar": serveArchive(t, "application/x-tar", newTarArchive(t, files...)),
+		"zip": serveArchive(t, "application/zip", newZipArchive(t, files...)),
+	}
+	for name, url := range archives {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := DownloadArchive(url, dir); err != nil {
+				t.Fatal(err)
+			}
+			for path, want := range map[string]string{"dir/a.txt": "hello", "b.txt": "world"} {
+				got, err := os.ReadFile(filepath.Join(dir, path))
+				if err != nil || string(got) != want {
+					t.Errorf("%s: got %q, %v", path, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadArchiveUnsafePaths(t *testing.T) {
+	for _, name := range []string{"../escape.txt", "/abs.txt", "dir/../../escape.txt"} {
+		url := serveArchive(t, "application/x-tar", newTarArchive(t, testArchiveFile{name, "x"}))
+		if err := DownloadArchive(url, t.TempDir()); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("%s: expected ErrUnsafePath, got %v", name, err)
+		}
+	}
+}
+
+func TestDownloadArchiveSymlinks(t *testing.T) {
+	outside := t.TempDir()
+	dir := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skip(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "target.txt"), filepath.Join(dir, "file.txt")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"link/evil.txt", "link/", "file.txt"} {
+		url := serveArchive(t, "application/x-tar", newTarArchive(t, testArchiveFile{name, "x"}))
+		if err := DownloadArchive(url, dir); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("%s: expected ErrUnsafePath, got %v", name, err)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Fatalf("wrote outside destDir: %v", entries)
+	}
+}
+
+func TestDownloadArchiveLimits(t *testing.T) {
+	url := serveArchive(t, "application/x-tar", newTarArchive(t, testArchiveFile{"a.txt", "0123456789"}, testArchiveFile{"b.txt", "0123456789"}))
+	cases := map[string]struct {
+		option DownloadOption
+		err    error
+	}{
+		"defaults":      {nil, nil},
+		"size fits":     {WithArchiveLimits(20, 0), nil},
+		"size exceeded": {WithArchiveLimits(15, 0), ErrArchiveLimit},
+		"entries fit":   {WithArchiveLimits(0, 2), nil},
+		"too many":      {WithArchiveLimits(0, 1), ErrArchiveLimit},
+	}
+	for name, c := range cases {
+		var o []DownloadOption
+		if c.option != nil {
+			o = append(o, c.option)
+		}
+		if err := DownloadArchive(url, t.TempDir(), o...); !errors.Is(err, c.err) {
+			t.Errorf("%s: got %v, want %v", name, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package dlutil
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type TokenSource func(ctx context.Context) (string, error)
+
+type tokenRefreshKey struct{}
+
+func TokenRefreshRequested(ctx context.Context) bool {
+	refresh, _ := ctx.Value(tokenRefreshKey{}).(bool)
+	return refresh
+}
+
+func WithBasicAuth(username, password string) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.BasicAuth = url.UserPassword(username, password)
+	}
+}
+
+func WithBearerToken(token string) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.BearerToken = token
+	}
+}
+
+func WithTokenSource(source TokenSource) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.TokenSource = source
+	}
+}
+
+func applyAuth(req *http.Request, opts *DownloadOptions, refreshToken bool) error {
+	if opts.BasicAuth != nil {
+		password, _ := opts.BasicAuth.Password()
+		req.SetBasicAuth(opts.BasicAuth.Username(), password)
+	}
+	if len(opts.BearerToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+opts.BearerToken)
+	}
+	if opts.TokenSource != nil {
+		ctx := req.Context()
+		if refreshToken {
+			ctx = context.WithValue(ctx, tokenRefreshKey{}, true)
+		}
+		token, err := opts.TokenSource(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if opts.DigestAuth != nil {
+		return opts.DigestAuth.authorize(req)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package dlutil
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrNoBackends = errors.New("no backends")
+
+type BalanceStrategy int
+
+const (
+	BalanceFailover BalanceStrategy = iota
+	BalanceRoundRobin
+	BalanceLeastPending
+)
+
+type BackendPool struct {
+	mu          sync.Mutex
+	backends    []*backend
+	maxFailures int
+	cooldown    time.Duration
+	strategy    BalanceStrategy
+	next        int
+}
+
+type backend struct {
+	url       string
+	failures  int
+	downUntil time.Time
+	pending   int
+}
+
+func NewBackendPool(baseURLs []string, maxFailures int, cooldown time.Duration) *BackendPool {
+	pool := &BackendPool{
+		maxFailures: maxFailures,
+		cooldown:    cooldown,
+	}
+	for _, url := range baseURLs {
+		pool.backends = append(pool.backends, &backend{url: url})
+	}
+	return pool
+}
+
+func WithBackends(pool *BackendPool) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.Backends = pool
+	}
+}
+
+func WithBaseURLs(baseURLs ...string) DownloadOption {
+	return WithBackends(NewBackendPool(baseURLs, 3, 30*time.Second))
+}
+
+func WithLoadBalancing(strategy BalanceStrategy, baseURLs ...string) DownloadOption {
+	pool := NewBackendPool(baseURLs, 3, 30*time.Second)
+	pool.SetBalancing(strategy)
+	return WithBackends(pool)
+}
+
+func (p *BackendPool) SetBalancing(strategy BalanceStrategy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.strategy = strategy
+}
+
+func (p *BackendPool) Healthy() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var healthy []string
+	for _, b := range p.backends {
+		if !now.Before(b.downUntil) {
+			healthy = append(healthy, b.url)
+		}
+	}
+	return healthy
+}
+
+func (p *BackendPool) order() []*backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	ordered := slices.Clone(p.backends)
+	if p.strategy == BalanceRoundRobin && len(ordered) > 0 {
+		start := p.next % len(ordered)
+		ordered = append(ordered[start:], ordered[:start]...)
+		p.next = start + 1
+	}
+	slices.SortStableFunc(ordered, func(a, b *backend) int {
+		aDown, bDown := now.Before(a.downUntil), now.Before(b.downUntil)
+		switch {
+		case aDown != bDown && bDown:
+			return -1
+		case aDown != bDown:
+			return 1
+		case p.strategy == BalanceLeastPending:
+			return cmp.Compare(a.pending, b.pending)
+		default:
+			return 0
+		}
+	})
+	return ordered
+}
+
+func (p *BackendPool) acquire(b *backend) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b.pending++
+}
+
+func (p *BackendPool) release(b *backend) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b.pending--
+}
+
+func (p *BackendPool) report(b *backend, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ok {
+		b.failures = 0
+		b.downUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if b.failures >= p.maxFailures {
+		b.failures = 0
+		b.downUntil = time.Now().Add(p.cooldown)
+	}
+}
+
+func doDownloadWithBackends(url string, opts *DownloadOptions) (io.ReadCloser, *http.Response, error) {
+	var reqBody []byte
+	if opts.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(opts.Body); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err := ErrNoBackends
+	for _, b := range opts.Backends.order() {
+		attempt := *opts
+		attempt.Backends = nil
+		attempt.BaseURL = b.url
+		if opts.Body != nil {
+			attempt.Body = bytes.NewReader(reqBody)
+		}
+		var body io.ReadCloser
+		var resp *http.Response
+		opts.Backends.acquire(b)
+		body, resp, err = doDownload(url, &attempt)
+		if err == nil {
+			opts.Backends.report(b, true)
+			var once sync.Once
+			return closeWith(body, func() { once.Do(func() { opts.Backends.release(b) }) }), resp, nil
+		}
+		opts.Backends.release(b)
+		if !isMirrorFailure(err) || opts.Ctx.Err() != nil {
+			return nil, nil, err
+		}
+		opts.Backends.report(b, false)
+	}
+	return nil, nil, err
+}
+
+func (p *BackendPool) CheckHealth(path string, o ...DownloadOption) []*HealthStatus {
+	statuses := make([]*HealthStatus, len(p.backends))
+	var wg sync.WaitGroup
+	for i, b := range p.backends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url, err := resolveURL(b.url, path)
+			if err != nil {
+				statuses[i] = &HealthStatus{URL: b.url, State: Unhealthy, Detail: err.Error()}
+			} else {
+				statuses[i], _ = CheckHealth(url, o...)
+			}
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if statuses[i].State == Unhealthy {
+				b.failures = 0
+				b.downUntil = time.Now().Add(p.cooldown)
+			} else {
+				b.failures = 0
+				b.downUntil = time.Time{}
+			}
+		}()
+	}
+	wg.Wait()
+	return statuses
+}
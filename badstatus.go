@@ -3,10 +3,12 @@ package dlutil
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
 type BadStatusError struct {
 	StatusCode int
+	RetryAfter time.Duration
 }
 
 func (e BadStatusError) Error() string {
@@ -16,3 +18,11 @@ func (e BadStatusError) Error() string {
 func BadStatus(statusCode int) *BadStatusError {
 	return &BadStatusError{StatusCode: statusCode}
 }
+
+func badStatusFromResponse(resp *http.Response) *BadStatusError {
+	err := BadStatus(resp.StatusCode)
+	if retryAfter, ok := parseRetryAfter(resp.Header); ok {
+		err.RetryAfter = retryAfter
+	}
+	return err
+}
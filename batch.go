@@ -0,0 +1,72 @@
+package dlutil
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"sync"
+)
+
+type BatchError struct {
+	Index int
+	URL   string
+	Err   error
+}
+
+func (e BatchError) Error() string {
+	return e.URL + ": " + e.Err.Error()
+}
+
+func (e BatchError) Unwrap() error {
+	return e.Err
+}
+
+func WithBatchHandler(handler func(index int, body io.Reader) error) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.BatchHandler = handler
+	}
+}
+
+func DownloadAll(urls []string, concurrency int, o ...DownloadOption) ([]Result, error) {
+	opts := newDownloadOptions(o)
+	concurrency = max(1, min(concurrency, len(urls)))
+
+	results := make([]Result, len(urls))
+	errs := make([]error, len(urls))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if err := downloadBatchItem(i, urls[i], &results[i], opts.BatchHandler, o); err != nil {
+					errs[i] = &BatchError{Index: i, URL: urls[i], Err: err}
+				}
+			}
+		}()
+	}
+	for i := range urls {
+		if opts.Ctx.Err() != nil {
+			errs[i] = &BatchError{Index: i, URL: urls[i], Err: opts.Ctx.Err()}
+			continue
+		}
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return results, errors.Join(errs...)
+}
+
+func downloadBatchItem(index int, url string, result *Result, handler func(index int, body io.Reader) error, o []DownloadOption) error {
+	body, err := Download(url, append(slices.Clip(o), WithResult(result))...)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if handler != nil {
+		return handler(index, body)
+	}
+	_, err = io.Copy(io.Discard, body)
+	return err
+}
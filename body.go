@@ -0,0 +1,44 @@
+package dlutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func WithJSONBody(v any) DownloadOption {
+	return func(do *DownloadOptions) {
+		content, err := json.Marshal(v)
+		if err != nil {
+			do.Body = &errReader{err: err}
+		} else {
+			do.Body = bytes.NewReader(content)
+		}
+		do.BodyContentType = "application/json"
+		do.PostByDefault = true
+	}
+}
+
+func WithFormBody(values url.Values) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.Body = strings.NewReader(values.Encode())
+		do.BodyContentType = "application/x-www-form-urlencoded"
+		do.PostByDefault = true
+	}
+}
+
+func defaultToPost(do *DownloadOptions) {
+	if do.PostByDefault && !do.MethodSet && (do.Method == http.MethodGet || len(do.Method) == 0) {
+		do.Method = http.MethodPost
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,29 @@
+package dlutil
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestBodyDefaultsToPost(t *testing.T) {
+	body := WithFormBody(url.Values{"a": {"1"}})
+	cases := map[string]struct {
+		o    []DownloadOption
+		want string
+	}{
+		"body only":          {[]DownloadOption{body}, http.MethodPost},
+		"json body":          {[]DownloadOption{WithJSONBody(map[string]int{"a": 1})}, http.MethodPost},
+		"multipart body":     {[]DownloadOption{WithMultipartForm(map[string]string{"a": "1"})}, http.MethodPost},
+		"explicit get first": {[]DownloadOption{WithMethod(http.MethodGet), body}, http.MethodGet},
+		"explicit get last":  {[]DownloadOption{body, WithMethod(http.MethodGet)}, http.MethodGet},
+		"put first":          {[]DownloadOption{WithMethod(http.MethodPut), body}, http.MethodPut},
+		"put last":           {[]DownloadOption{body, WithMethod(http.MethodPut)}, http.MethodPut},
+		"no body":            {nil, http.MethodGet},
+	}
+	for name, c := range cases {
+		if opts := newDownloadOptions(c.o); opts.Method != c.want {
+			t.Errorf("%s: got %s, want %s", name, opts.Method, c.want)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package dlutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/razzie/razcache"
+)
+
+const cacheEntryMagic = "\x00dlutil-cache\n"
+
+var ErrCorruptCacheEntry = errors.New("corrupt cache entry")
+
+type cacheEntry struct {
+	Expires      time.Time   `json:"expires"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Vary         []string    `json:"vary,omitempty"`
+	Variants     []string    `json:"variants,omitempty"`
+	Encoding     string      `json:"encoding,omitempty"`
+	Header       http.Header `json:"header,omitempty"`
+	body         string
+}
+
+func WithCacheHeaders(names ...string) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.CacheHeaders = append(do.CacheHeaders, names...)
+	}
+}
+
+func newCacheEntry(opts *DownloadOptions, header http.Header, content []byte) *cacheEntry {
+	e := &cacheEntry{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Vary:         varyHeaders(header),
+		Header:       make(http.Header),
+		body:         string(content),
+	}
+	for _, name := range append([]string{"Content-Type", "Link"}, opts.CacheHeaders...) {
+		if values := header.Values(name); len(values) > 0 {
+			e.Header[http.CanonicalHeaderKey(name)] = slices.Clone(values)
+		}
+	}
+	return e
+}
+
+func (e *cacheEntry) fresh() bool {
+	return e.Expires.IsZero() || time.Now().Before(e.Expires)
+}
+
+func WithCacheBase64() DownloadOption {
+	return func(do *DownloadOptions) {
+		do.CacheBase64 = true
+	}
+}
+
+func encodeCacheEntry(e *cacheEntry, useBase64 bool) (string, error) {
+	body := e.body
+	e.Encoding = ""
+	if useBase64 {
+		e.Encoding = "base64"
+		body = base64.StdEncoding.EncodeToString([]byte(body))
+	}
+	meta, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return cacheEntryMagic + string(meta) + "\n" + body, nil
+}
+
+func decodeCacheEntry(content string) (*cacheEntry, error) {
+	rest, ok := strings.CutPrefix(content, cacheEntryMagic)
+	if !ok {
+		return &cacheEntry{body: content}, nil
+	}
+	meta, body, ok := strings.Cut(rest, "\n")
+	if !ok {
+		return nil, ErrCorruptCacheEntry
+	}
+	var e cacheEntry
+	if err := json.Unmarshal([]byte(meta), &e); err != nil {
+		return nil, err
+	}
+	switch e.Encoding {
+	case "":
+		e.body = body
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+		e.body = string(decoded)
+	default:
+		return nil, ErrCorruptCacheEntry
+	}
+	return &e, nil
+}
+
+func WithStaleWhileRevalidate(extraTTL time.Duration) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.StaleRevalidate = extraTTL
+	}
+}
+
+func WithStaleIfError(grace time.Duration) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.StaleIfError = grace
+	}
+}
+
+func getCacheEntry(url, key string, opts *DownloadOptions) (*cacheEntry, error) {
+	content, err := opts.Cache.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeCacheEntry(content)
+	if err != nil || len(e.Variants) == 0 {
+		return e, err
+	}
+	header, err := cacheRequestHeader(url, opts)
+	if err != nil {
+		return nil, err
+	}
+	if content, err = opts.Cache.Get(variantKey(key, e.Variants, header)); err != nil {
+		return nil, err
+	}
+	return decodeCacheEntry(content)
+}
+
+func readCache(url string, opts *DownloadOptions) (*cacheEntry, bool) {
+	e, err := getCacheEntry(url, cacheKey(url, opts), opts)
+	if err != nil {
+		return nil, false
+	}
+	if !e.fresh() {
+		if opts.StaleRevalidate <= 0 || !time.Now().Before(e.Expires.Add(opts.StaleRevalidate)) {
+			return e, false
+		}
+		revalidate(url, opts, e)
+	}
+	return e, true
+}
+
+func (e *cacheEntry) usableOnError(grace time.Duration) bool {
+	return grace > 0 && (e.Expires.IsZero() || time.Now().Before(e.Expires.Add(grace)))
+}
+
+func cachedResponse(url string, opts *DownloadOptions, e *cacheEntry) (io.ReadCloser, *http.Response, error) {
+	resp := &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+	}
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	} else if len(opts.AcceptContentType) > 0 && !matchContentType(resp, opts.AcceptContentType) {
+		return nil, nil, errors.New("bad content-type: " + resp.Header.Get("Content-Type"))
+	}
+	if req, err := http.NewRequestWithContext(opts.Ctx, opts.Method, url, nil); err == nil {
+		resp.Request = req
+	}
+	if opts.Result != nil {
+		opts.Result.CacheHit = true
+	}
+	return resp.Body, resp, nil
+}
+
+func cacheFresh(url string, opts *DownloadOptions) bool {
+	e, err := getCacheEntry(url, url, opts)
+	return err == nil && e.fresh()
+}
+
+func writeCache(url string, opts *DownloadOptions, resp *http.Response, e *cacheEntry) error {
+	ttl, forever := opts.CacheTTL, opts.CacheTTL <= 0
+	if opts.HTTPCache {
+		freshness, explicit, store := httpFreshness(resp.Header)
+		if !store {
+			return nil
+		}
+		if explicit {
+			ttl, forever = max(freshness, 0), false
+		}
+	}
+	if slices.Contains(e.Vary, "*") {
+		return nil
+	}
+	retention := max(opts.StaleRevalidate, opts.StaleIfError)
+	if e.hasValidator() {
+		retention = max(retention, opts.CacheTTL)
+	}
+
+	var storeTTL time.Duration
+	if !forever {
+		if ttl <= 0 && retention <= 0 {
+			return nil
+		}
+		e.Expires = time.Now().Add(ttl)
+		storeTTL = ttl + retention
+	}
+	content, err := encodeCacheEntry(e, opts.CacheBase64)
+	if err != nil {
+		return err
+	}
+
+	key := cacheKey(url, opts)
+	if len(e.Vary) > 0 {
+		marker, err := encodeCacheEntry(&cacheEntry{Variants: e.Vary}, false)
+		if err != nil {
+			return err
+		}
+		reqHeader, err := cacheRequestHeader(url, opts)
+		if err != nil {
+			return err
+		}
+		if resp.Request != nil {
+			for _, name := range e.Vary {
+				if !slices.Equal(reqHeader.Values(name), resp.Request.Header.Values(name)) {
+					return nil
+				}
+			}
+		}
+		if err := opts.Cache.Set(key, marker, storeTTL); err != nil {
+			return err
+		}
+		key = variantKey(key, e.Vary, reqHeader)
+	}
+	return opts.Cache.Set(key, content, storeTTL)
+}
+
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); len(name) > 0 {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func cacheRequestHeader(url string, opts *DownloadOptions) (http.Header, error) {
+	req, err := http.NewRequestWithContext(opts.Ctx, opts.Method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	lookup := *opts
+	lookup.TokenSource = nil
+	lookup.DigestAuth = nil
+	if err := applyRequestHeaders(req, &lookup, false); err != nil {
+		return nil, err
+	}
+	if opts.Client != nil && opts.Client.Jar != nil {
+		for _, cookie := range opts.Client.Jar.Cookies(req.URL) {
+			req.AddCookie(cookie)
+		}
+	}
+	return req.Header, nil
+}
+
+func variantKey(key string, vary []string, header http.Header) string {
+	h := sha256.New()
+	for _, name := range vary {
+		io.WriteString(h, name+"="+strings.Join(header.Values(name), ",")+"\n")
+	}
+	return key + "#" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (e *cacheEntry) hasValidator() bool {
+	return len(e.ETag) > 0 || len(e.LastModified) > 0
+}
+
+func fetchCached(url string, opts *DownloadOptions, cached *cacheEntry) (io.ReadCloser, *http.Response, error) {
+	if cached == nil || !cached.hasValidator() {
+		return fetch(url, opts)
+	}
+	conditional := *opts
+	conditional.Header = opts.Header.Clone()
+	if conditional.Header == nil {
+		conditional.Header = make(http.Header)
+	}
+	if len(cached.ETag) > 0 {
+		conditional.Header.Set("If-None-Match", cached.ETag)
+	}
+	if len(cached.LastModified) > 0 {
+		conditional.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+	body, resp, err := fetch(url, &conditional)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusNotModified {
+		return body, resp, nil
+	}
+	body.Close()
+	refreshed := *cached
+	if etag := resp.Header.Get("ETag"); len(etag) > 0 {
+		refreshed.ETag = etag
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); len(lastModified) > 0 {
+		refreshed.LastModified = lastModified
+	}
+	if vary := varyHeaders(resp.Header); len(vary) > 0 {
+		refreshed.Vary = vary
+	}
+	writeCache(url, opts, resp, &refreshed)
+	return cachedResponse(url, opts, cached)
+}
+
+type revalidationKey struct {
+	cache razcache.Cache
+	key   string
+}
+
+var revalidating sync.Map
+
+func revalidate(url string, opts *DownloadOptions, cached *cacheEntry) {
+	key := revalidationKey{cache: opts.Cache, key: cacheKey(url, opts)}
+	if _, loaded := revalidating.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	refresh := *opts
+	refresh.Ctx = context.WithoutCancel(opts.Ctx)
+	refresh.Result = nil
+	go func() {
+		defer revalidating.Delete(key)
+		body, _, err := fetchCached(url, &refresh, cached)
+		if err == nil {
+			body.Close()
+		}
+	}()
+}
@@ -0,0 +1,246 @@
+package dlutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/razzie/razcache"
+)
+
+type testCache struct {
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newTestCache() *testCache {
+	return &testCache{
+		values:  make(map[string]string),
+		expires: make(map[string]time.Time),
+	}
+}
+
+func (c *testCache) Set(key, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	if ttl > 0 {
+		c.expires[key] = time.Now().Add(ttl)
+	} else {
+		delete(c.expires, key)
+	}
+	return nil
+}
+
+func (c *testCache) Get(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[key]
+	if expires, has := c.expires[key]; has && !time.Now().Before(expires) {
+		ok = false
+	}
+	if !ok {
+		return "", razcache.ErrNotFound
+	}
+	return value, nil
+}
+
+func (c *testCache) Del(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	delete(c.expires, key)
+	return nil
+}
+
+func (c *testCache) GetTTL(key string) (time.Duration, error) {
+	if _, err := c.Get(key); err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if expires, ok := c.expires[key]; ok {
+		return time.Until(expires), nil
+	}
+	return 0, nil
+}
+
+func (c *testCache) SetTTL(key string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires[key] = time.Now().Add(ttl)
+	return nil
+}
+
+func (c *testCache) SubCache(prefix string) razcache.Cache {
+	return c
+}
+
+func (c *testCache) Close() error {
+	return nil
+}
+
+func TestStaleWhileRevalidateWindow(t *testing.T) {
+	var version atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "v%d", version.Add(1))
+	}))
+	defer srv.Close()
+
+	o := []DownloadOption{
+		WithCache(newTestCache(), "", 20*time.Millisecond),
+		WithStaleWhileRevalidate(20 * time.Millisecond),
+		WithStaleIfError(time.Hour),
+	}
+	if s, err := DownloadString(srv.URL, o...); err != nil || s != "v1" {
+		t.Fatalf("got %q, %v", s, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if s, err := DownloadString(srv.URL, o...); err != nil || s != "v2" {
+		t.Fatalf("entry past the revalidation window was served stale: %q, %v", s, err)
+	}
+}
+
+func TestCacheBinaryRoundTrip(t *testing.T) {
+	allBytes := make([]byte, 256)
+	for i := range allBytes {
+		allBytes[i] = byte(i)
+	}
+	payloads := map[string][]byte{
+		"invalid utf8":   []byte("\xff\xfe\x00"),
+		"envelope magic": []byte(cacheEntryMagic + "{\"encoding\":\"bogus\"}\n\xff"),
+		"all bytes":      allBytes,
+		"empty":          {},
+	}
+	encodings := map[string][]DownloadOption{
+		"envelope": nil,
+		"base64":   {WithCacheBase64()},
+	}
+	for encoding, extra := range encodings {
+		for _, ttl := range []time.Duration{0, time.Minute} {
+			for name, payload := range payloads {
+				t.Run(fmt.Sprintf("%s/%s/ttl=%v", encoding, name, ttl), func(t *testing.T) {
+					opts := newDownloadOptions(append([]DownloadOption{WithCache(newTestCache(), "", ttl)}, extra...))
+					url := "http://example.com/blob"
+					if err := writeCache(url, &opts, &http.Response{Header: http.Header{}}, newCacheEntry(&opts, http.Header{}, payload)); err != nil {
+						t.Fatal(err)
+					}
+					e, ok := readCache(url, &opts)
+					if !ok {
+						t.Fatal("cache miss")
+					}
+					if e.body != string(payload) {
+						t.Fatalf("got %q, want %q", e.body, payload)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestCacheBinaryDownload(t *testing.T) {
+	payload := []byte(cacheEntryMagic + "\xff\xfe\x00\x1f\x8b")
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(payload)
+	}))
+	defer srv.Close()
+
+	for _, base64 := range []bool{false, true} {
+		o := []DownloadOption{WithCache(newTestCache(), "", time.Minute)}
+		if base64 {
+			o = append(o, WithCacheBase64())
+		}
+		for range 2 {
+			got, err := DownloadBytes(srv.URL, o...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(payload) {
+				t.Fatalf("base64=%v: got %q, want %q", base64, got, payload)
+			}
+		}
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("expected 2 origin requests, got %d", n)
+	}
+}
+
+func TestCacheVaryOnSentHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Authorization")
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	cache := newTestCache()
+	for range 2 {
+		for _, token := range []string{"alice", "bob"} {
+			got, err := DownloadString(srv.URL, WithCache(cache, "", time.Minute), WithBearerToken(token))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "Bearer " + token; got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		}
+	}
+}
+
+func TestCacheVaryLookupSkipsTokenSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept-Language")
+		fmt.Fprint(w, r.Header.Get("Accept-Language"))
+	}))
+	defer srv.Close()
+
+	var tokens atomic.Int32
+	source := func(context.Context) (string, error) {
+		tokens.Add(1)
+		return "token", nil
+	}
+	cache := newTestCache()
+	for range 2 {
+		got, err := DownloadString(srv.URL, WithCache(cache, "", time.Minute), WithTokenSource(source), WithHeader("Accept-Language", "hu"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "hu" {
+			t.Fatalf("got %q", got)
+		}
+	}
+	if n := tokens.Load(); n != 1 {
+		t.Fatalf("expected 1 token fetch, got %d", n)
+	}
+}
+
+func TestCacheVarySkipsHeadersAddedAfterLookup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept-Language")
+		fmt.Fprint(w, r.Header.Get("Accept-Language"))
+	}))
+	defer srv.Close()
+
+	cache := newTestCache()
+	hook := OnRequest(func(req *http.Request) error {
+		req.Header.Set("Accept-Language", "de")
+		return nil
+	})
+	got, err := DownloadString(srv.URL, WithCache(cache, "", time.Minute), WithHeader("Accept-Language", "en"), hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "de" {
+		t.Fatalf("got %q", got)
+	}
+	if len(cache.values) != 0 {
+		t.Fatalf("expected nothing cached, got %v", cache.values)
+	}
+}
@@ -0,0 +1,20 @@
+package dlutil
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+func DownloadCBOR[T any](url string, o ...DownloadOption) (*T, error) {
+	body, err := Download(url, append(o, WithAcceptContentType("application/cbor"))...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	decoder := cbor.NewDecoder(body)
+	result := new(T)
+	if err := decoder.Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
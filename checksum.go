@@ -0,0 +1,165 @@
+package dlutil
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	neturl "net/url"
+	"path"
+	"strings"
+)
+
+type ChecksumMismatchError struct {
+	Expected []byte
+	Actual   []byte
+}
+
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch: expected %s, got %s", hex.EncodeToString(e.Expected), hex.EncodeToString(e.Actual))
+}
+
+func WithChecksum(hash crypto.Hash, expected []byte) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.ChecksumHash = hash
+		do.Checksum = expected
+	}
+}
+
+func WithChecksumURL(hash crypto.Hash, sumsURL string) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.ChecksumHash = hash
+		do.ChecksumURL = sumsURL
+	}
+}
+
+func FetchChecksum(sumsURL, filename string, o ...DownloadOption) ([]byte, error) {
+	content, err := DownloadBytes(sumsURL, o...)
+	if err != nil {
+		return nil, err
+	}
+	return parseChecksumFile(content, filename)
+}
+
+func resolveChecksumURL(url string, opts *DownloadOptions) error {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return err
+	}
+	filename := path.Base(u.Path)
+
+	sumsURL := opts.ChecksumURL
+	if len(sumsURL) == 0 {
+		ext, ok := checksumFileExts[opts.ChecksumHash]
+		if !ok {
+			return errors.New("no default checksum file for hash: " + opts.ChecksumHash.String())
+		}
+		sums := *u
+		sums.Path += ext
+		if len(sums.RawPath) > 0 {
+			sums.RawPath += ext
+		}
+		sums.Fragment = ""
+		sumsURL = sums.String()
+	}
+
+	checksum, err := FetchChecksum(sumsURL, filename, inheritOptions(opts))
+	if err != nil {
+		return err
+	}
+	opts.Checksum = checksum
+	return nil
+}
+
+var checksumFileExts = map[crypto.Hash]string{
+	crypto.MD5:    ".md5",
+	crypto.SHA1:   ".sha1",
+	crypto.SHA256: ".sha256",
+	crypto.SHA512: ".sha512",
+}
+
+func parseChecksumFile(content []byte, filename string) ([]byte, error) {
+	var unnamed []byte
+	entries := 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var sum, name string
+		if open := strings.Index(line, " ("); open > 0 && strings.Contains(line[open:], ") = ") {
+			// BSD style: SHA256 (filename) = hex
+			rest := line[open+2:]
+			closing := strings.LastIndex(rest, ") = ")
+			if closing < 0 {
+				continue
+			}
+			name, sum = rest[:closing], rest[closing+4:]
+		} else {
+			// GNU style: hex  filename or hex *filename
+			var found bool
+			sum, name, found = strings.Cut(line, " ")
+			if found {
+				name = strings.TrimPrefix(strings.TrimLeft(name, " "), "*")
+			}
+		}
+
+		checksum, err := hex.DecodeString(sum)
+		if err != nil {
+			continue
+		}
+		entries++
+		if name == filename || path.Base(name) == filename {
+			return checksum, nil
+		}
+		if len(name) == 0 {
+			unnamed = checksum
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if entries == 1 && unnamed != nil {
+		return unnamed, nil
+	}
+	return nil, errors.New("checksum not found for file: " + filename)
+}
+
+type checksumReader struct {
+	r        io.ReadCloser
+	hash     hash.Hash
+	expected []byte
+	err      error
+}
+
+func newChecksumReader(r io.ReadCloser, h crypto.Hash, expected []byte) (io.ReadCloser, error) {
+	if !h.Available() {
+		return nil, errors.New("checksum hash not available: " + h.String())
+	}
+	return &checksumReader{r: r, hash: h.New(), expected: expected}, nil
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if actual := r.hash.Sum(nil); !bytes.Equal(actual, r.expected) {
+			r.err = &ChecksumMismatchError{Expected: r.expected, Actual: actual}
+			return n, r.err
+		}
+	}
+	return n, err
+}
+
+func (r *checksumReader) Close() error {
+	return r.r.Close()
+}
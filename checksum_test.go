@@ -0,0 +1,75 @@
+package dlutil
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChecksumURLWithQuery(t *testing.T) {
+	content := []byte("file content")
+	sum := sha256.Sum256(content)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/file.bin":
+			w.Write(content)
+		case "/file.bin.sha256":
+			w.Write([]byte(hex.EncodeToString(sum[:]) + "  file.bin\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	got, err := DownloadBytes(srv.URL+"/file.bin?token=secret#top", WithChecksumURL(crypto.SHA256, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(content) {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestParseChecksumFile(t *testing.T) {
+	const sum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	tests := []struct {
+		name     string
+		content  string
+		filename string
+		want     string
+	}{
+		{"gnu", sum + "  file.bin\n", "file.bin", sum},
+		{"gnu binary", sum + " *dir/file.bin\n", "file.bin", sum},
+		{"bsd", "SHA256 (file.bin) = " + sum + "\n", "file.bin", sum},
+		{"bsd parens in name", "SHA256 (file (1).bin) = " + sum + "\n", "file (1).bin", sum},
+		{"single unnamed", sum + "\n", "file.bin", sum},
+		{"comments", "# sums\n\n" + sum + "  file.bin\n", "file.bin", sum},
+		{"malformed lines", "a) = b (c\nx (y\n) = (\nSHA256 (\nzz  file.bin\n" + sum + "  file.bin\n", "file.bin", sum},
+		{"malformed only", "a) = b (c\n", "file.bin", ""},
+		{"not found", sum + "  other.bin\n", "file.bin", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChecksumFile([]byte(tt.content), tt.filename)
+			if len(tt.want) == 0 {
+				if err == nil {
+					t.Fatalf("expected error, got %x", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Fatalf("got %x", got)
+			}
+		})
+	}
+}
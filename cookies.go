@@ -0,0 +1,17 @@
+package dlutil
+
+import (
+	"net/http"
+)
+
+func WithCookieJar(jar http.CookieJar) DownloadOption {
+	return WithClientOption(func(c *http.Client) {
+		c.Jar = jar
+	})
+}
+
+func WithCookies(cookies ...*http.Cookie) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.Cookies = append(do.Cookies, cookies...)
+	}
+}
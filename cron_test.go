@@ -0,0 +1,64 @@
+package dlutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	// Wednesday
+	start := time.Date(2025, time.January, 1, 10, 7, 0, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, time.January, 1, 10, 15, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2025, time.January, 1, 10, 25, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2025, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 5-7", time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, time.January, 1, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2025, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Errorf("%s: %v", tt.spec, err)
+			continue
+		}
+		if got := schedule(start); !got.Equal(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, spec := range []string{"* * * *", "60 * * * *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "@never"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("%s: expected error", spec)
+		}
+	}
+}
+
+func TestRefresherNilSchedule(t *testing.T) {
+	r := NewRefresher(newTestCache(), time.Minute, nil)
+	r.Close()
+}
+
+func TestEveryNonPositive(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Every(%v): expected panic", interval)
+				}
+			}()
+			Every(interval)
+		}()
+	}
+}
@@ -0,0 +1,267 @@
+package dlutil
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	csvContentTypes = "text/csv, application/csv, text/tab-separated-values, text/plain"
+	utf8BOM         = "\ufeff"
+)
+
+func WithCSVDelimiter(delimiter rune) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.CSVDelimiter = delimiter
+	}
+}
+
+func WithCSVNoHeader() DownloadOption {
+	return func(do *DownloadOptions) {
+		do.CSVNoHeader = true
+	}
+}
+
+func DownloadCSV[T any](url string, o ...DownloadOption) ([]T, error) {
+	var results []T
+	for row, err := range DownloadCSVRows[T](url, o...) {
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *row)
+	}
+	return results, nil
+}
+
+func DownloadCSVRows[T any](url string, o ...DownloadOption) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		opts := newDownloadOptions(append(o, WithAcceptContentType(csvContentTypes)))
+		body, resp, err := doDownload(url, &opts)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer body.Close()
+
+		for row, err := range csvRows(body, reflect.TypeFor[T](), csvDelimiter(url, resp, &opts), opts.CSVNoHeader) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(row.Interface().(*T), nil) {
+				return
+			}
+		}
+	}
+}
+
+func decodeCSV(r io.Reader, out any, delimiter rune) error {
+	slice := reflect.ValueOf(out)
+	if slice.Kind() != reflect.Pointer || slice.Elem().Kind() != reflect.Slice {
+		return errors.New("csv output must be a pointer to a slice: " + slice.Type().String())
+	}
+	slice = slice.Elem()
+	for row, err := range csvRows(r, slice.Type().Elem(), delimiter, false) {
+		if err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, row.Elem()))
+	}
+	return nil
+}
+
+func csvRows(body io.Reader, rowType reflect.Type, delimiter rune, noHeader bool) iter.Seq2[reflect.Value, error] {
+	return func(yield func(reflect.Value, error) bool) {
+		if rowType.Kind() != reflect.Struct {
+			yield(reflect.Value{}, errors.New("csv row type must be a struct: "+rowType.String()))
+			return
+		}
+
+		br := bufio.NewReader(body)
+		if bom, err := br.Peek(len(utf8BOM)); err == nil && string(bom) == utf8BOM {
+			br.Discard(len(utf8BOM))
+		}
+		r := csv.NewReader(br)
+		r.Comma = delimiter
+		r.FieldsPerRecord = -1
+
+		var header []string
+		if !noHeader {
+			var err error
+			if header, err = r.Read(); err != nil {
+				if err != io.EOF {
+					yield(reflect.Value{}, err)
+				}
+				return
+			}
+		}
+		columns := csvColumns(rowType, header)
+
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(reflect.Value{}, err)
+				return
+			}
+			row := reflect.New(rowType)
+			line, _ := r.FieldPos(0)
+			if err := decodeCSVRecord(row.Elem(), record, columns, line); err != nil {
+				yield(reflect.Value{}, err)
+				return
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+	}
+}
+
+func csvDelimiter(url string, resp *http.Response, opts *DownloadOptions) rune {
+	if opts.CSVDelimiter != 0 {
+		return opts.CSVDelimiter
+	}
+	if resp != nil {
+		if contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); contentType == "text/tab-separated-values" {
+			return '\t'
+		}
+	}
+	if strings.HasSuffix(strings.ToLower(strings.SplitN(url, "?", 2)[0]), ".tsv") {
+		return '\t'
+	}
+	return ','
+}
+
+func csvColumns(t reflect.Type, header []string) []int {
+	var names []string
+	var fields []int
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag, _, _ = strings.Cut(tag, ","); len(tag) > 0 {
+				name = tag
+			}
+		}
+		names = append(names, name)
+		fields = append(fields, i)
+	}
+
+	if header == nil {
+		return fields
+	}
+	columns := make([]int, len(header))
+	for i, column := range header {
+		columns[i] = -1
+		column = strings.TrimSpace(column)
+		for j, name := range names {
+			if strings.EqualFold(name, column) {
+				columns[i] = fields[j]
+				break
+			}
+		}
+	}
+	return columns
+}
+
+type CSVFieldError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e CSVFieldError) Error() string {
+	return fmt.Sprintf("csv line %d column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e CSVFieldError) Unwrap() error {
+	return e.Err
+}
+
+func decodeCSVRecord(row reflect.Value, record []string, columns []int, line int) error {
+	for i, value := range record {
+		if i >= len(columns) || columns[i] < 0 {
+			continue
+		}
+		if err := setCSVField(row.Field(columns[i]), value); err != nil {
+			return &CSVFieldError{Line: line, Column: i + 1, Err: err}
+		}
+	}
+	return nil
+}
+
+func setCSVField(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Pointer {
+		if len(value) == 0 {
+			return nil
+		}
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	value = strings.TrimSpace(value)
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		if len(value) == 0 {
+			return nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if len(value) == 0 {
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if len(value) == 0 {
+			return nil
+		}
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if len(value) == 0 {
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return errors.New("unsupported field type: " + field.Type().String())
+	}
+	return nil
+}
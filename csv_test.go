@@ -0,0 +1,35 @@
+package dlutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDownloadCSVByteOrderMark(t *testing.T) {
+	type row struct {
+		Name string `csv:"name"`
+		Age  int    `csv:"age"`
+	}
+	bodies := map[string]string{
+		"plain":  "\ufeffname,age\nalice,30\n",
+		"quoted": "\ufeff\"name\",\"age\"\nalice,30\n",
+	}
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/csv")
+				w.Write([]byte(body))
+			}))
+			defer srv.Close()
+
+			rows, err := DownloadCSV[row](srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 1 || rows[0] != (row{Name: "alice", Age: 30}) {
+				t.Fatalf("got %+v", rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package dlutil
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+type Decoder func(r io.Reader, out any) error
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		"application/json": func(r io.Reader, out any) error {
+			return json.NewDecoder(r).Decode(out)
+		},
+		"application/xml": func(r io.Reader, out any) error {
+			return xml.NewDecoder(r).Decode(out)
+		},
+		"text/xml": func(r io.Reader, out any) error {
+			return xml.NewDecoder(r).Decode(out)
+		},
+		"application/yaml":   decodeYAML,
+		"application/x-yaml": decodeYAML,
+		"text/yaml":          decodeYAML,
+		"text/csv": func(r io.Reader, out any) error {
+			return decodeCSV(r, out, ',')
+		},
+		"text/tab-separated-values": func(r io.Reader, out any) error {
+			return decodeCSV(r, out, '\t')
+		},
+		"application/msgpack":   decodeMsgpack,
+		"application/x-msgpack": decodeMsgpack,
+		"application/cbor": func(r io.Reader, out any) error {
+			return cbor.NewDecoder(r).Decode(out)
+		},
+		"application/toml": func(r io.Reader, out any) error {
+			_, err := toml.NewDecoder(r).Decode(out)
+			return err
+		},
+	}
+)
+
+func decodeYAML(r io.Reader, out any) error {
+	return yaml.NewDecoder(r).Decode(out)
+}
+
+func decodeMsgpack(r io.Reader, out any) error {
+	return msgpack.NewDecoder(r).Decode(out)
+}
+
+func RegisterDecoder(contentType string, decoder Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[contentType] = decoder
+}
+
+func lookupDecoder(contentType string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	parsedType, _, _ := mime.ParseMediaType(contentType)
+	if decoder, ok := decoders[parsedType]; ok {
+		return decoder, true
+	}
+	if _, suffix, ok := strings.Cut(parsedType, "+"); ok {
+		decoder, ok := decoders["application/"+suffix]
+		return decoder, ok
+	}
+	return nil, false
+}
+
+func registeredContentTypes() string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+
+	contentTypes := make([]string, 0, len(decoders))
+	for contentType := range decoders {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+	return strings.Join(contentTypes, ", ")
+}
+
+func DownloadAny(url string, out any, o ...DownloadOption) error {
+	opts := newDownloadOptions(o)
+	if len(opts.Header.Get("Accept")) == 0 {
+		WithHeader("Accept", registeredContentTypes())(&opts)
+	}
+	body, resp, err := doDownload(url, &opts)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var contentType string
+	if resp != nil {
+		contentType = resp.Header.Get("Content-Type")
+	}
+	decoder, ok := lookupDecoder(contentType)
+	if !ok {
+		return errors.New("no decoder for content-type: " + contentType)
+	}
+	return decoder(body, out)
+}
+
+type DecodeFunc[T any] func(r io.Reader) (T, error)
+
+func DownloadAs[T any](url string, decode DecodeFunc[T], o ...DownloadOption) (T, error) {
+	body, err := Download(url, o...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer body.Close()
+
+	return decode(body)
+}
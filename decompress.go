@@ -0,0 +1,117 @@
+package dlutil
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	autoDecompressEncodings = "gzip, br, zstd"
+	minRatioCheckSize       = 1 << 20
+
+	DefaultMaxDecompressedSize = 1 << 30
+	DefaultMaxCompressionRatio = 0
+)
+
+var ErrDecompressionBomb = errors.New("decompression limit exceeded")
+
+func WithAutoDecompress() DownloadOption {
+	return func(do *DownloadOptions) {
+		do.AutoDecompress = true
+	}
+}
+
+func WithDecompressionLimits(maxSize int64, maxRatio float64) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.MaxDecompressedSize = maxSize
+		do.MaxCompressionRatio = maxRatio
+	}
+}
+
+func WithNoDecompressionLimits() DownloadOption {
+	return WithDecompressionLimits(0, 0)
+}
+
+func decompressResponse(resp *http.Response, opts *DownloadOptions) error {
+	if resp.ContentLength == 0 || resp.Request != nil && resp.Request.Method == http.MethodHead {
+		return nil
+	}
+
+	compressed := &countingReader{r: resp.Body}
+	var decoded io.ReadCloser
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(compressed)
+		if err != nil {
+			return err
+		}
+		decoded = zr
+	case "br":
+		decoded = io.NopCloser(brotli.NewReader(compressed))
+	case "zstd":
+		zr, err := zstd.NewReader(compressed)
+		if err != nil {
+			return err
+		}
+		decoded = zr.IOReadCloser()
+	default:
+		return nil
+	}
+
+	var r io.Reader = decoded
+	if opts.MaxDecompressedSize > 0 || opts.MaxCompressionRatio > 0 {
+		r = &bombReader{
+			r:          decoded,
+			compressed: compressed,
+			maxSize:    opts.MaxDecompressedSize,
+			maxRatio:   opts.MaxCompressionRatio,
+		}
+	}
+	body := resp.Body
+	resp.Body = &readCloser{Reader: r, close: func() error {
+		decoded.Close()
+		return body.Close()
+	}}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type bombReader struct {
+	r          io.Reader
+	compressed *countingReader
+	decoded    int64
+	maxSize    int64
+	maxRatio   float64
+}
+
+func (r *bombReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.decoded += int64(n)
+	if r.maxSize > 0 && r.decoded > r.maxSize {
+		return n, ErrDecompressionBomb
+	}
+	if r.maxRatio > 0 && r.decoded > minRatioCheckSize && float64(r.decoded) > r.maxRatio*float64(max(r.compressed.n, 1)) {
+		return n, ErrDecompressionBomb
+	}
+	return n, err
+}
@@ -0,0 +1,39 @@
+package dlutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecompressionLimitsDefault(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(make([]byte, 4<<20))
+	zw.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	got, err := DownloadBytes(srv.URL, WithAutoDecompress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4<<20 {
+		t.Fatalf("got %d bytes", len(got))
+	}
+	if _, err := DownloadBytes(srv.URL, WithAutoDecompress(), WithDecompressionLimits(DefaultMaxDecompressedSize, 100)); !errors.Is(err, ErrDecompressionBomb) {
+		t.Fatalf("expected ErrDecompressionBomb from ratio limit, got %v", err)
+	}
+	if _, err := DownloadBytes(srv.URL, WithAutoDecompress(), WithDecompressionLimits(1<<20, 0)); !errors.Is(err, ErrDecompressionBomb) {
+		t.Fatalf("expected ErrDecompressionBomb from size limit, got %v", err)
+	}
+	if DefaultDownloadOptions.MaxDecompressedSize != 1<<30 {
+		t.Fatalf("default size cap is %d", DefaultDownloadOptions.MaxDecompressedSize)
+	}
+}
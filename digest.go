@@ -0,0 +1,218 @@
+package dlutil
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type DigestAuth struct {
+	username  string
+	password  string
+	mu        sync.Mutex
+	challenge map[string]string
+	nc        uint32
+}
+
+func WithDigestAuth(username, password string) DownloadOption {
+	auth := &DigestAuth{
+		username: username,
+		password: password,
+	}
+	return func(do *DownloadOptions) {
+		do.DigestAuth = auth
+	}
+}
+
+func (a *DigestAuth) setChallenge(header http.Header) bool {
+	var best map[string]string
+	for _, value := range header.Values("WWW-Authenticate") {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		params := parseAuthParams(rest)
+		if len(params["nonce"]) == 0 || digestHash(params["algorithm"]) == nil {
+			continue
+		}
+		if best == nil || digestStrength(params["algorithm"]) > digestStrength(best["algorithm"]) {
+			best = params
+		}
+	}
+	if best == nil {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.challenge = best
+	a.nc = 0
+	return true
+}
+
+func (a *DigestAuth) authorize(req *http.Request) error {
+	a.mu.Lock()
+	challenge := a.challenge
+	a.nc++
+	nc := fmt.Sprintf("%08x", a.nc)
+	a.mu.Unlock()
+	if challenge == nil {
+		return nil
+	}
+
+	algorithm := challenge["algorithm"]
+	newHash := digestHash(algorithm)
+	h := func(s string) string {
+		hh := newHash()
+		hh.Write([]byte(s))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	realm := challenge["realm"]
+	nonce := challenge["nonce"]
+	uri := req.URL.RequestURI()
+	cnonce := newCNonce()
+
+	ha1 := h(a.username + ":" + realm + ":" + a.password)
+	if strings.HasSuffix(strings.ToLower(algorithm), "-sess") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+
+	qop := selectQop(challenge["qop"])
+	ha2 := h(req.Method + ":" + uri)
+	if qop == "auth-int" {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return err
+		}
+		ha2 = h(req.Method + ":" + uri + ":" + h(string(body)))
+	}
+
+	var response string
+	if len(qop) > 0 {
+		response = h(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":" + qop + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	}
+
+	username := a.username
+	userhash := strings.EqualFold(challenge["userhash"], "true")
+	if userhash {
+		username = h(a.username + ":" + realm)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Digest username=%s, realm=%s, nonce=%s, uri=%s, response=%s",
+		quoteAuthParam(username), quoteAuthParam(realm), quoteAuthParam(nonce), quoteAuthParam(uri), quoteAuthParam(response))
+	if len(algorithm) > 0 {
+		fmt.Fprintf(&sb, ", algorithm=%s", algorithm)
+	}
+	if opaque, ok := challenge["opaque"]; ok {
+		fmt.Fprintf(&sb, ", opaque=%s", quoteAuthParam(opaque))
+	}
+	if len(qop) > 0 {
+		fmt.Fprintf(&sb, ", qop=%s, nc=%s, cnonce=%s", qop, nc, quoteAuthParam(cnonce))
+	}
+	if userhash {
+		sb.WriteString(", userhash=true")
+	}
+	req.Header.Set("Authorization", sb.String())
+	return nil
+}
+
+func digestHash(algorithm string) func() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "", "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	case "SHA-512-256":
+		return sha512.New512_256
+	default:
+		return nil
+	}
+}
+
+func digestStrength(algorithm string) int {
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "SHA-512-256":
+		return 2
+	case "SHA-256":
+		return 1
+	default:
+		return 0
+	}
+}
+
+func selectQop(offered string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	var authInt bool
+	for _, qop := range strings.Split(offered, ",") {
+		switch strings.TrimSpace(qop) {
+		case "auth":
+			return "auth"
+		case "auth-int":
+			authInt = true
+		}
+	}
+	if authInt {
+		return "auth-int"
+	}
+	return ""
+}
+
+func newCNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func quoteAuthParam(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if len(s) == 0 {
+			return params
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var sb strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				sb.WriteByte(s[i])
+			}
+			value = sb.String()
+			s = s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+	}
+}
@@ -0,0 +1,198 @@
+package dlutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrResolveWithSOCKS5 = errors.New("custom DNS resolution cannot be combined with a SOCKS5 proxy")
+
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+type DNSCache struct {
+	mu      sync.Mutex
+	entries map[dnsCacheKey]dnsCacheEntry
+}
+
+type dnsCacheKey struct {
+	resolver Resolver
+	host     string
+}
+
+type dnsCacheEntry struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+var DefaultDNSCache = NewDNSCache()
+
+func NewDNSCache() *DNSCache {
+	return &DNSCache{entries: make(map[dnsCacheKey]dnsCacheEntry)}
+}
+
+func (c *DNSCache) Invalidate(hosts ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		for _, host := range hosts {
+			if key.host == strings.ToLower(host) {
+				delete(c.entries, key)
+			}
+		}
+	}
+}
+
+func (c *DNSCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+func (c *DNSCache) lookup(ctx context.Context, resolver Resolver, ttl time.Duration, host string) ([]netip.Addr, error) {
+	if !reflect.ValueOf(resolver).Comparable() {
+		return resolver.LookupNetIP(ctx, "ip", host)
+	}
+	key := dnsCacheKey{resolver: resolver, host: strings.ToLower(host)}
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[key] = dnsCacheEntry{addrs: addrs, expires: time.Now().Add(ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
+
+func WithResolver(resolver Resolver) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.Resolver = resolver
+	}
+}
+
+func WithDNSCache(ttl time.Duration) DownloadOption {
+	return func(do *DownloadOptions) {
+		if do.DNSCache == nil {
+			do.DNSCache = DefaultDNSCache
+		}
+		do.DNSCacheTTL = ttl
+	}
+}
+
+func WithResolve(host, ip string) DownloadOption {
+	return func(do *DownloadOptions) {
+		if do.ResolveOverrides == nil {
+			do.ResolveOverrides = make(map[string]string)
+		}
+		do.ResolveOverrides[strings.ToLower(host)] = ip
+	}
+}
+
+func needsResolvingDialer(opts *DownloadOptions) bool {
+	return opts.Resolver != nil || opts.DNSCache != nil || opts.SSRFProtection || len(opts.ResolveOverrides) > 0
+}
+
+func newLookup(opts *DownloadOptions) func(ctx context.Context, host string) ([]netip.Addr, error) {
+	var resolver Resolver = net.DefaultResolver
+	if opts.Resolver != nil {
+		resolver = opts.Resolver
+	}
+	cache, ttl := opts.DNSCache, opts.DNSCacheTTL
+	overrides := opts.ResolveOverrides
+	return func(ctx context.Context, host string) ([]netip.Addr, error) {
+		if override, ok := overrides[strings.ToLower(host)]; ok {
+			ip, err := netip.ParseAddr(override)
+			if err != nil {
+				return nil, err
+			}
+			return []netip.Addr{ip}, nil
+		}
+		if ip, err := netip.ParseAddr(host); err == nil {
+			return []netip.Addr{ip}, nil
+		}
+		if cache != nil {
+			return cache.lookup(ctx, resolver, ttl, host)
+		}
+		return resolver.LookupNetIP(ctx, "ip", host)
+	}
+}
+
+func applyResolvingDialer(t *http.Transport, opts *DownloadOptions) error {
+	dial := transportDialer(t)
+	lookup := newLookup(opts)
+	ssrf := opts.SSRFProtection
+	if opts.SOCKS5 {
+		if opts.Resolver != nil || opts.DNSCache != nil || len(opts.ResolveOverrides) > 0 {
+			return ErrResolveWithSOCKS5
+		}
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			ips, err := lookup(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkAddrs(host, ips); err != nil {
+				return nil, err
+			}
+			return dial(ctx, network, addr)
+		}
+		return nil
+	}
+	isProxy := func(string) bool { return false }
+	if ssrf {
+		isProxy = guardProxy(t, lookup)
+	}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if ssrf && !isProxy(addr) {
+			if err := checkAddrs(host, ips); err != nil {
+				return nil, err
+			}
+		}
+		var conn net.Conn
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		for _, ip := range ips {
+			if conn, err = dial(ctx, network, net.JoinHostPort(ip.Unmap().String(), port)); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+	return nil
+}
+
+func transportDialer(t *http.Transport) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if t.DialContext != nil {
+		return t.DialContext
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	return dialer.DialContext
+}
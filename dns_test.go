@@ -0,0 +1,93 @@
+package dlutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type staticResolver struct {
+	addr    netip.Addr
+	lookups atomic.Int32
+}
+
+func (r *staticResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	r.lookups.Add(1)
+	return []netip.Addr{r.addr}, nil
+}
+
+type resolverFunc func(ctx context.Context, network, host string) ([]netip.Addr, error)
+
+func (f resolverFunc) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	return f(ctx, network, host)
+}
+
+func TestDNSCachePerResolver(t *testing.T) {
+	cache := NewDNSCache()
+	a := &staticResolver{addr: netip.MustParseAddr("10.0.0.1")}
+	b := &staticResolver{addr: netip.MustParseAddr("10.0.0.2")}
+	for range 2 {
+		for _, r := range []*staticResolver{a, b} {
+			addrs, err := cache.lookup(context.Background(), r, time.Minute, "Example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(addrs) != 1 || addrs[0] != r.addr {
+				t.Fatalf("got %v, want %v", addrs, r.addr)
+			}
+		}
+	}
+	if a.lookups.Load() != 1 || b.lookups.Load() != 1 {
+		t.Fatalf("got %d and %d lookups", a.lookups.Load(), b.lookups.Load())
+	}
+
+	cache.Invalidate("example.com")
+	cache.lookup(context.Background(), a, time.Minute, "example.com")
+	if a.lookups.Load() != 2 {
+		t.Fatalf("expected a lookup after Invalidate, got %d", a.lookups.Load())
+	}
+}
+
+func TestDNSCacheUncomparableResolver(t *testing.T) {
+	cache := NewDNSCache()
+	var lookups atomic.Int32
+	r := resolverFunc(func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		lookups.Add(1)
+		return []netip.Addr{netip.MustParseAddr("10.0.0.1")}, nil
+	})
+	for range 2 {
+		if _, err := cache.lookup(context.Background(), r, time.Minute, "example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lookups.Load() != 2 {
+		t.Fatalf("expected the cache to be bypassed, got %d lookups", lookups.Load())
+	}
+}
+
+func TestDNSCacheDownload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer srv.Close()
+
+	r := &staticResolver{addr: netip.MustParseAddr("127.0.0.1")}
+	url := strings.Replace(srv.URL, "127.0.0.1", "dlutil.test", 1)
+	for range 2 {
+		got, err := DownloadString(url, WithResolver(r), WithDNSCache(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(got, "dlutil.test:") {
+			t.Fatalf("got %q", got)
+		}
+	}
+	if n := r.lookups.Load(); n != 1 {
+		t.Fatalf("expected 1 lookup, got %d", n)
+	}
+}
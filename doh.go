@@ -0,0 +1,123 @@
+package dlutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type DoHResolver struct {
+	serverURL string
+	client    *http.Client
+}
+
+func NewDoHResolver(serverURL string, client *http.Client) *DoHResolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &DoHResolver{
+		serverURL: serverURL,
+		client:    client,
+	}
+}
+
+func WithDoHResolver(serverURL string) DownloadOption {
+	return WithResolver(NewDoHResolver(serverURL, nil))
+}
+
+func (r *DoHResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	var types []dnsmessage.Type
+	switch network {
+	case "ip":
+		types = []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}
+	case "ip4":
+		types = []dnsmessage.Type{dnsmessage.TypeA}
+	case "ip6":
+		types = []dnsmessage.Type{dnsmessage.TypeAAAA}
+	default:
+		return nil, errors.New("unsupported network: " + network)
+	}
+
+	var addrs []netip.Addr
+	var lastErr error
+	for _, qtype := range types {
+		answers, err := r.query(ctx, host, qtype)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		addrs = append(addrs, answers...)
+	}
+	if len(addrs) == 0 {
+		if lastErr == nil {
+			lastErr = &net.DNSError{Err: "no such host", Name: host, Server: r.serverURL, IsNotFound: true}
+		}
+		return nil, lastErr
+	}
+	return addrs, nil
+}
+
+func (r *DoHResolver) query(ctx context.Context, host string, qtype dnsmessage.Type) ([]netip.Addr, error) {
+	name, err := dnsmessage.NewName(dnsName(host))
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := DownloadBytes(r.serverURL,
+		WithContext(ctx),
+		WithClient(r.client),
+		WithMethod(http.MethodPost),
+		WithBody(bytes.NewReader(packed), "application/dns-message"),
+		WithHeader("Accept", "application/dns-message"),
+		WithAcceptContentType("application/dns-message"))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(content); err != nil {
+		return nil, err
+	}
+	switch resp.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, &net.DNSError{Err: "no such host", Name: host, Server: r.serverURL, IsNotFound: true}
+	default:
+		return nil, &net.DNSError{Err: "server returned " + resp.RCode.String(), Name: host, Server: r.serverURL}
+	}
+
+	var addrs []netip.Addr
+	for _, answer := range resp.Answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, netip.AddrFrom4(body.A))
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, netip.AddrFrom16(body.AAAA))
+		}
+	}
+	return addrs, nil
+}
+
+func dnsName(host string) string {
+	if len(host) > 0 && host[len(host)-1] == '.' {
+		return host
+	}
+	return host + "."
+}
@@ -3,11 +3,13 @@ package dlutil
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"encoding/json"
 	"errors"
 	"io"
 	"mime"
 	"net/http"
+	neturl "net/url"
 	"strings"
 	"time"
 
@@ -19,25 +21,116 @@ var DefaultDownloadOptions = DownloadOptions{
 	Ctx:    context.Background(),
 	Client: http.DefaultClient,
 	Method: "GET",
+
+	MaxDecompressedSize: DefaultMaxDecompressedSize,
+	MaxCompressionRatio: DefaultMaxCompressionRatio,
+	MaxArchiveSize:      DefaultMaxArchiveSize,
+	MaxArchiveEntries:   DefaultMaxArchiveEntries,
 }
 
 type DownloadOptions struct {
-	Ctx               context.Context
-	Client            *http.Client
-	Cache             razcache.Cache
-	CacheKey          string
-	CacheTTL          time.Duration
-	GenError          func(r io.Reader, code int) error
-	Method            string
-	Body              io.Reader
-	BodyContentType   string
-	Header            http.Header
-	AcceptContentType string
-	IgnoreStatusCode  bool
+	Ctx                 context.Context
+	Client              *http.Client
+	Cache               razcache.Cache
+	CacheKey            string
+	CacheTTL            time.Duration
+	StaleRevalidate     time.Duration
+	StaleIfError        time.Duration
+	HTTPCache           bool
+	CacheBase64         bool
+	CacheHeaders        []string
+	Concurrency         int
+	GenError            func(r io.Reader, code int) error
+	JSONErrorDecoder    func(decoder *json.Decoder, code int) error
+	StrictJSON          bool
+	JSONDecoderOptions  []func(decoder *json.Decoder)
+	JSONUnmarshal       func(data []byte, v any) error
+	JSONSchema          []byte
+	Method              string
+	MethodSet           bool
+	Body                io.Reader
+	PostByDefault       bool
+	BodyContentType     string
+	Header              http.Header
+	AcceptContentType   string
+	IgnoreStatusCode    bool
+	RetryPolicy         RetryPolicy
+	RetryNonIdempotent  bool
+	ParallelChunks      int
+	ChunkSize           int64
+	BandwidthLimiter    *BandwidthLimiter
+	Limiter             *Limiter
+	MinSpeed            int64
+	MinSpeedWindow      time.Duration
+	MaxSize             int64
+	AutoDecompress      bool
+	MaxDecompressedSize int64
+	MaxCompressionRatio float64
+	ChecksumHash        crypto.Hash
+	Checksum            []byte
+	ChecksumURL         string
+	SignatureVerifier   SignatureVerifier
+	SignatureURL        string
+	ArchiveEntryFunc    func(entry ArchiveEntry) error
+	MaxArchiveSize      int64
+	MaxArchiveEntries   int
+	BaseURL             string
+	Middlewares         []Middleware
+	RequestHooks        []func(req *http.Request) error
+	ResponseHooks       []func(resp *http.Response) error
+	ErrorHooks          []func(req *http.Request, err error)
+	Result              *Result
+	CSVDelimiter        rune
+	CSVNoHeader         bool
+	MaxLineLength       int
+	Query               neturl.Values
+	PathParams          map[string]string
+	BasicAuth           *neturl.Userinfo
+	BearerToken         string
+	TokenSource         TokenSource
+	DigestAuth          *DigestAuth
+	AWSCredentials      *AWSCredentials
+	AWSRegion           string
+	AWSService          string
+	AWSUnsignedPayload  bool
+	RequestSigners      []RequestSigner
+	TransportOptions    []*transportOption
+	TransportTuning     *TransportTuning
+	ClientOptions       []func(c *http.Client)
+	Cookies             []*http.Cookie
+	MaxRedirects        int
+	NoRedirects         bool
+	RedirectHooks       []func(prev, next *neturl.URL) error
+	RedirectCredentials bool
+	AllowedHosts        []string
+	BlockedHosts        []string
+	SSRFProtection      bool
+	Resolver            Resolver
+	DNSCache            *DNSCache
+	DNSCacheTTL         time.Duration
+	ResolveOverrides    map[string]string
+	SOCKS5              bool
+	UnixSocket          string
+	HTTP3               bool
+	Timeout             time.Duration
+	AttemptTimeout      time.Duration
+	HedgeDelay          time.Duration
+	HedgeMaxExtra       int
+	MirrorStrategy      MirrorStrategy
+	Backends            *BackendPool
+	BatchHandler        func(index int, body io.Reader) error
 }
 
 type DownloadOption func(*DownloadOptions)
 
+func WithOptions(o ...DownloadOption) DownloadOption {
+	return func(do *DownloadOptions) {
+		for _, o := range o {
+			o(do)
+		}
+	}
+}
+
 func WithContext(ctx context.Context) DownloadOption {
 	return func(do *DownloadOptions) {
 		if ctx == nil {
@@ -68,9 +161,9 @@ func WithCache(cache razcache.Cache, key string, ttl time.Duration) DownloadOpti
 
 func WithErrorType[T error]() DownloadOption {
 	return func(do *DownloadOptions) {
-		do.GenError = func(r io.Reader, code int) error {
+		do.GenError = nil
+		do.JSONErrorDecoder = func(decoder *json.Decoder, code int) error {
 			var result T
-			decoder := json.NewDecoder(r)
 			if err := decoder.Decode(&result); err != nil {
 				return BadStatus(code)
 			}
@@ -79,9 +172,32 @@ func WithErrorType[T error]() DownloadOption {
 	}
 }
 
+func WithStrictJSON() DownloadOption {
+	return func(do *DownloadOptions) {
+		do.StrictJSON = true
+	}
+}
+
+func WithJSONDecoderOptions(o ...func(decoder *json.Decoder)) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.JSONDecoderOptions = append(do.JSONDecoderOptions, o...)
+	}
+}
+
+func WithJSONUseNumber() DownloadOption {
+	return WithJSONDecoderOptions((*json.Decoder).UseNumber)
+}
+
+func WithJSONUnmarshal(unmarshal func(data []byte, v any) error) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.JSONUnmarshal = unmarshal
+	}
+}
+
 func WithMethod(method string) DownloadOption {
 	return func(do *DownloadOptions) {
 		do.Method = method
+		do.MethodSet = true
 	}
 }
 
@@ -104,6 +220,26 @@ func WithHeader(key, value0 string, values ...string) DownloadOption {
 	}
 }
 
+func WithQuery(key, value string) DownloadOption {
+	return func(do *DownloadOptions) {
+		if do.Query == nil {
+			do.Query = make(neturl.Values)
+		}
+		do.Query.Add(key, value)
+	}
+}
+
+func WithQueryValues(values neturl.Values) DownloadOption {
+	return func(do *DownloadOptions) {
+		if do.Query == nil {
+			do.Query = make(neturl.Values)
+		}
+		for key, vals := range values {
+			do.Query[key] = append(do.Query[key], vals...)
+		}
+	}
+}
+
 func WithFakeUserAgent() DownloadOption {
 	return WithHeader("User-Agent", fakeuseragent.RandomUserAgent())
 }
@@ -121,61 +257,261 @@ func WithIgnoreStatusCode() DownloadOption {
 }
 
 func Download(url string, o ...DownloadOption) (io.ReadCloser, error) {
+	opts := newDownloadOptions(o)
+	body, _, err := doDownload(url, &opts)
+	return body, err
+}
+
+func newDownloadOptions(o []DownloadOption) DownloadOptions {
 	opts := DefaultDownloadOptions
 	for _, o := range o {
 		o(&opts)
 	}
+	defaultToPost(&opts)
+	return opts
+}
 
-	if opts.Cache != nil {
-		content, err := opts.Cache.Get(opts.CacheKey)
-		if err == nil {
-			return io.NopCloser(strings.NewReader(content)), nil
+func doDownload(url string, opts *DownloadOptions) (io.ReadCloser, *http.Response, error) {
+	if opts.Timeout > 0 {
+		return doDownloadWithTimeout(url, opts)
+	}
+	if opts.Backends != nil {
+		return doDownloadWithBackends(url, opts)
+	}
+	if needsTransport(opts) {
+		return doDownloadWithTransport(url, opts)
+	}
+	if len(opts.ClientOptions) > 0 {
+		deriveClient(opts)
+	}
+	applyRedirectPolicy(opts)
+	url, err := requestURL(url, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Result == nil {
+		return download(url, opts)
+	}
+
+	start := time.Now()
+	*opts.Result = Result{URL: url}
+	body, resp, err := download(url, opts)
+	if err != nil {
+		opts.Result.Duration = time.Since(start)
+		return nil, nil, err
+	}
+	if resp != nil {
+		opts.Result.StatusCode = resp.StatusCode
+		if resp.Request != nil && resp.Request.URL != nil {
+			opts.Result.URL = resp.Request.URL.String()
 		}
+	} else {
+		opts.Result.StatusCode = http.StatusOK
+		opts.Result.CacheHit = true
 	}
+	return &resultReader{r: body, result: opts.Result, start: start}, resp, nil
+}
 
-	req, err := http.NewRequestWithContext(opts.Ctx, opts.Method, url, opts.Body)
+func requestURL(url string, opts *DownloadOptions) (string, error) {
+	url, err := expandPathParams(url, opts.PathParams)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	for key, values := range opts.Header {
-		req.Header[key] = values
+	if url, err = resolveURL(opts.BaseURL, url); err != nil {
+		return "", err
 	}
-	if len(opts.BodyContentType) > 0 {
-		req.Header.Set("Content-Type", opts.BodyContentType)
+	return appendQuery(url, opts.Query)
+}
+
+func cacheKey(url string, opts *DownloadOptions) string {
+	if len(opts.CacheKey) > 0 {
+		return opts.CacheKey
+	}
+	return url
+}
+
+func download(url string, opts *DownloadOptions) (io.ReadCloser, *http.Response, error) {
+	if opts.Cache == nil {
+		return fetch(url, opts)
+	}
+	cached, ok := readCache(url, opts)
+	if ok {
+		return cachedResponse(url, opts, cached)
+	}
+	body, resp, err := fetchCached(url, opts, cached)
+	if err != nil && cached != nil && cached.usableOnError(opts.StaleIfError) && isMirrorFailure(err) {
+		return cachedResponse(url, opts, cached)
+	}
+	return body, resp, err
+}
+
+func fetch(url string, opts *DownloadOptions) (io.ReadCloser, *http.Response, error) {
+	if opts.ChecksumHash != 0 && opts.Checksum == nil {
+		if err := resolveChecksumURL(url, opts); err != nil {
+			return nil, nil, err
+		}
 	}
-	resp, err := opts.Client.Do(req)
+
+	resp, err := doRequest(url, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	contentLength := resp.ContentLength
+	if opts.MinSpeed > 0 && opts.MinSpeedWindow > 0 {
+		resp.Body = newMinSpeedReader(resp.Body, opts.MinSpeed, opts.MinSpeedWindow)
+	}
+	if opts.BandwidthLimiter != nil {
+		resp.Body = newRateLimitedReader(opts.Ctx, resp.Body, opts.BandwidthLimiter)
+	}
+	if opts.Limiter != nil && opts.Limiter.bandwidth != nil {
+		resp.Body = newRateLimitedReader(opts.Ctx, resp.Body, opts.Limiter.bandwidth)
+	}
+	if opts.AutoDecompress {
+		if err := decompressResponse(resp, opts); err != nil {
+			resp.Body.Close()
+			return nil, nil, err
+		}
 	}
 	body := resp.Body
+	if resp.StatusCode == http.StatusNotModified && opts.Cache != nil {
+		return body, resp, nil
+	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
 		if opts.GenError != nil && matchContentType(resp, "application/json") {
 			defer body.Close()
-			return nil, opts.GenError(body, resp.StatusCode)
+			return nil, nil, opts.GenError(body, resp.StatusCode)
+		}
+		if opts.JSONErrorDecoder != nil && matchContentType(resp, "application/json") {
+			defer body.Close()
+			return nil, nil, opts.JSONErrorDecoder(newJSONDecoder(body, opts), resp.StatusCode)
 		}
 		if !opts.IgnoreStatusCode {
 			body.Close()
-			return nil, BadStatus(resp.StatusCode)
+			return nil, nil, badStatusFromResponse(resp)
 		}
 	}
 
 	if len(opts.AcceptContentType) > 0 && !matchContentType(resp, opts.AcceptContentType) {
 		body.Close()
-		return nil, errors.New("bad content-type: " + resp.Header.Get("Content-Type"))
+		return nil, nil, errors.New("bad content-type: " + resp.Header.Get("Content-Type"))
+	}
+
+	if opts.MaxSize > 0 {
+		if contentLength > opts.MaxSize {
+			body.Close()
+			return nil, nil, ErrTooLarge
+		}
+		body = newMaxSizeReader(body, opts.MaxSize)
+	}
+
+	if opts.ChecksumHash != 0 {
+		if body, err = newChecksumReader(body, opts.ChecksumHash, opts.Checksum); err != nil {
+			resp.Body.Close()
+			return nil, nil, err
+		}
+	}
+
+	if opts.SignatureVerifier != nil {
+		if body, err = verifySignature(body, opts); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	if opts.Cache != nil {
 		content, err := io.ReadAll(body)
+		body.Close()
 		if err != nil {
-			body.Close()
-			return nil, err
+			return nil, nil, err
 		}
-		opts.Cache.Set(opts.CacheKey, string(content), opts.CacheTTL)
+		writeCache(url, opts, resp, newCacheEntry(opts, resp.Header, content))
 		body = io.NopCloser(bytes.NewReader(content))
 	}
 
-	return body, nil
+	return body, resp, nil
+}
+
+func appendQuery(url string, query neturl.Values) (string, error) {
+	if len(query) == 0 {
+		return url, nil
+	}
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return "", err
+	}
+	if len(u.RawQuery) > 0 {
+		u.RawQuery += "&" + query.Encode()
+	} else {
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
+
+func newRequest(url string, opts *DownloadOptions, body io.Reader, refreshToken bool) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(opts.Ctx, opts.Method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHost(req.URL, opts); err != nil {
+		return nil, err
+	}
+	if err := applyRequestHeaders(req, opts, refreshToken); err != nil {
+		return nil, err
+	}
+	if opts.AWSCredentials != nil {
+		if err := signAWSSigV4(req, opts.AWSCredentials, opts.AWSRegion, opts.AWSService, opts.AWSUnsignedPayload, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	for _, sign := range opts.RequestSigners {
+		if err := sign(req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func applyRequestHeaders(req *http.Request, opts *DownloadOptions, refreshToken bool) error {
+	for key, values := range opts.Header {
+		req.Header[key] = values
+	}
+	if len(opts.BodyContentType) > 0 {
+		req.Header.Set("Content-Type", opts.BodyContentType)
+	}
+	if opts.AutoDecompress && len(req.Header.Get("Accept-Encoding")) == 0 {
+		req.Header.Set("Accept-Encoding", autoDecompressEncodings)
+	}
+	for _, cookie := range opts.Cookies {
+		req.AddCookie(cookie)
+	}
+	return applyAuth(req, opts, refreshToken)
+}
+
+func inheritOptions(opts *DownloadOptions) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.Ctx = opts.Ctx
+		do.Client = opts.Client
+		do.Header = opts.Header
+		do.RetryPolicy = opts.RetryPolicy
+		do.Limiter = opts.Limiter
+		do.AllowedHosts = opts.AllowedHosts
+		do.BlockedHosts = opts.BlockedHosts
+	}
+}
+
+func send(req *http.Request, opts *DownloadOptions) (*http.Response, error) {
+	rt := runHooks(func(req *http.Request) (*http.Response, error) {
+		if opts.Limiter != nil {
+			if err := opts.Limiter.WaitRequest(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+		return opts.Client.Do(req)
+	}, opts)
+	for i := len(opts.Middlewares) - 1; i >= 0; i-- {
+		rt = opts.Middlewares[i](rt)
+	}
+	return rt(req)
 }
 
 func DownloadBytes(url string, o ...DownloadOption) ([]byte, error) {
@@ -193,22 +529,97 @@ func DownloadBytes(url string, o ...DownloadOption) ([]byte, error) {
 	return content, nil
 }
 
+func DownloadInto(w io.Writer, url string, o ...DownloadOption) (int64, error) {
+	body, err := Download(url, o...)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	return io.Copy(w, body)
+}
+
 func DownloadJSON[T any](url string, o ...DownloadOption) (*T, error) {
-	body, err := Download(url, append(o, WithAcceptContentType("application/json"))...)
+	opts := newDownloadOptions(append(o, WithAcceptContentType("application/json")))
+	body, _, err := doDownload(url, &opts)
 	if err != nil {
 		return nil, err
 	}
 	defer body.Close()
 
-	decoder := json.NewDecoder(body)
+	var r io.Reader = body
+	if opts.JSONSchema != nil {
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateJSONSchema(opts.JSONSchema, content); err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(content)
+	}
+
 	result := new(T)
+	if opts.JSONUnmarshal != nil {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := opts.JSONUnmarshal(content, result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	decoder := newJSONDecoder(r, &opts)
 	if err := decoder.Decode(result); err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func matchContentType(resp *http.Response, contentType string) bool {
+func newJSONDecoder(r io.Reader, opts *DownloadOptions) *json.Decoder {
+	decoder := json.NewDecoder(r)
+	if opts.StrictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	for _, o := range opts.JSONDecoderOptions {
+		o(decoder)
+	}
+	return decoder
+}
+
+func matchContentType(resp *http.Response, contentTypes string) bool {
 	parsedType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
-	return contentType == parsedType
+	for _, contentType := range strings.Split(contentTypes, ",") {
+		contentType = strings.TrimSpace(contentType)
+		if prefix, ok := strings.CutSuffix(contentType, "/*"); ok {
+			if strings.HasPrefix(parsedType, prefix+"/") {
+				return true
+			}
+		} else if contentType == parsedType {
+			return true
+		}
+	}
+	return false
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (rc *readCloser) Close() error {
+	return rc.close()
+}
+
+func closeWith(body io.ReadCloser, fn func()) io.ReadCloser {
+	return &readCloser{
+		Reader: body,
+		close: func() error {
+			err := body.Close()
+			fn()
+			return err
+		},
+	}
 }
@@ -0,0 +1,234 @@
+package dlutil
+
+import (
+	"errors"
+	"image"
+	"io"
+	"iter"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+	"google.golang.org/protobuf/proto"
+)
+
+type Downloader struct {
+	mu   sync.RWMutex
+	opts []DownloadOption
+}
+
+func NewDownloader(o ...DownloadOption) *Downloader {
+	return &Downloader{opts: o}
+}
+
+func (d *Downloader) options(o []DownloadOption) []DownloadOption {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append(slices.Clip(d.opts), o...)
+}
+
+func (d *Downloader) Get(url string, o ...DownloadOption) (io.ReadCloser, error) {
+	return Download(url, d.options(o)...)
+}
+
+func (d *Downloader) GetBytes(url string, o ...DownloadOption) ([]byte, error) {
+	return DownloadBytes(url, d.options(o)...)
+}
+
+func (d *Downloader) GetFile(url, path string, o ...DownloadOption) (int64, error) {
+	return DownloadFile(url, path, d.options(o)...)
+}
+
+func (d *Downloader) GetArchive(url, destDir string, o ...DownloadOption) error {
+	return DownloadArchive(url, destDir, d.options(o)...)
+}
+
+func (d *Downloader) GetFromMirrors(urls []string, o ...DownloadOption) (io.ReadCloser, string, error) {
+	return DownloadFromMirrors(urls, d.options(o)...)
+}
+
+func (d *Downloader) GetAll(urls []string, concurrency int, o ...DownloadOption) ([]Result, error) {
+	return DownloadAll(urls, concurrency, d.options(o)...)
+}
+
+func (d *Downloader) Prefetch(urls []string, o ...DownloadOption) error {
+	return Prefetch(urls, d.options(o)...)
+}
+
+func GetJSON[T any](d *Downloader, url string, o ...DownloadOption) (*T, error) {
+	return DownloadJSON[T](url, d.options(o)...)
+}
+
+func WithBaseURL(baseURL string) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.BaseURL = baseURL
+	}
+}
+
+func PathParam(name, value string) DownloadOption {
+	return func(do *DownloadOptions) {
+		if do.PathParams == nil {
+			do.PathParams = make(map[string]string)
+		}
+		do.PathParams[name] = value
+	}
+}
+
+func expandPathParams(url string, params map[string]string) (string, error) {
+	if len(params) == 0 {
+		return url, nil
+	}
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(url, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(url[start:], '}')
+		if end < 0 {
+			break
+		}
+		name := url[start+1 : start+end]
+		value, ok := params[name]
+		if !ok {
+			return "", errors.New("missing path parameter: " + name)
+		}
+		if value == "." || value == ".." {
+			return "", errors.New("invalid path parameter: " + name)
+		}
+		sb.WriteString(url[:start])
+		sb.WriteString(neturl.PathEscape(value))
+		url = url[start+end+1:]
+	}
+	sb.WriteString(url)
+	return sb.String(), nil
+}
+
+func resolveURL(baseURL, url string) (string, error) {
+	if len(baseURL) == 0 {
+		return url, nil
+	}
+	ref, err := neturl.Parse(url)
+	if err != nil {
+		return "", err
+	}
+	if ref.IsAbs() || len(ref.Host) > 0 {
+		return url, nil
+	}
+	base, err := neturl.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	if len(ref.Path) > 0 {
+		rawPath := strings.TrimSuffix(base.EscapedPath(), "/") + "/" + strings.TrimLeft(ref.EscapedPath(), "/")
+		if base.Path, err = neturl.PathUnescape(rawPath); err != nil {
+			return "", err
+		}
+		base.RawPath = rawPath
+	}
+	if len(ref.Path) > 0 || len(ref.RawQuery) > 0 {
+		base.RawQuery = ref.RawQuery
+	}
+	base.Fragment = ref.Fragment
+	return base.String(), nil
+}
+
+func (d *Downloader) GetWithResponse(url string, o ...DownloadOption) (*Response, error) {
+	return DownloadWithResponse(url, d.options(o)...)
+}
+
+func (d *Downloader) Stat(url string, o ...DownloadOption) (*RemoteFileInfo, error) {
+	return Stat(url, d.options(o)...)
+}
+
+func (d *Downloader) Exists(url string, o ...DownloadOption) (bool, error) {
+	return Exists(url, d.options(o)...)
+}
+
+func (d *Downloader) GetString(url string, o ...DownloadOption) (string, error) {
+	return DownloadString(url, d.options(o)...)
+}
+
+func (d *Downloader) GetInto(w io.Writer, url string, o ...DownloadOption) (int64, error) {
+	return DownloadInto(w, url, d.options(o)...)
+}
+
+func GetTOML[T any](d *Downloader, url string, o ...DownloadOption) (*T, error) {
+	return DownloadTOML[T](url, d.options(o)...)
+}
+
+func GetCSV[T any](d *Downloader, url string, o ...DownloadOption) ([]T, error) {
+	return DownloadCSV[T](url, d.options(o)...)
+}
+
+func GetMsgpack[T any](d *Downloader, url string, o ...DownloadOption) (*T, error) {
+	return DownloadMsgpack[T](url, d.options(o)...)
+}
+
+func GetCBOR[T any](d *Downloader, url string, o ...DownloadOption) (*T, error) {
+	return DownloadCBOR[T](url, d.options(o)...)
+}
+
+func GetProto[T proto.Message](d *Downloader, url string, o ...DownloadOption) (T, error) {
+	return DownloadProto[T](url, d.options(o)...)
+}
+
+func GetGob[T any](d *Downloader, url string, o ...DownloadOption) (*T, error) {
+	return DownloadGob[T](url, d.options(o)...)
+}
+
+func (d *Downloader) GetHTML(url string, o ...DownloadOption) (*html.Node, error) {
+	return DownloadHTML(url, d.options(o)...)
+}
+
+func (d *Downloader) GetImage(url string, o ...DownloadOption) (image.Image, string, error) {
+	return DownloadImage(url, d.options(o)...)
+}
+
+func (d *Downloader) GetImageConfig(url string, o ...DownloadOption) (image.Config, string, error) {
+	return DownloadImageConfig(url, d.options(o)...)
+}
+
+func (d *Downloader) GetLines(url string, o ...DownloadOption) iter.Seq2[string, error] {
+	return DownloadLines(url, d.options(o)...)
+}
+
+func GetNDJSON[T any](d *Downloader, url string, o ...DownloadOption) iter.Seq2[T, error] {
+	return DownloadNDJSON[T](url, d.options(o)...)
+}
+
+func GetJSONStream[T any](d *Downloader, url string, o ...DownloadOption) iter.Seq2[T, error] {
+	return DownloadJSONStream[T](url, d.options(o)...)
+}
+
+func (d *Downloader) GetAny(url string, out any, o ...DownloadOption) error {
+	return DownloadAny(url, out, d.options(o)...)
+}
+
+func GetAs[T any](d *Downloader, url string, decode DecodeFunc[T], o ...DownloadOption) (T, error) {
+	return DownloadAs(url, decode, d.options(o)...)
+}
+
+func (d *Downloader) GetSSE(url string, o ...DownloadOption) iter.Seq2[Event, error] {
+	return DownloadSSE(url, d.options(o)...)
+}
+
+func (d *Downloader) Poll(url string, interval time.Duration, handler PollHandler, o ...DownloadOption) error {
+	return Poll(url, interval, handler, d.options(o)...)
+}
+
+func GetPaged[T any](d *Downloader, url string, o ...DownloadOption) iter.Seq2[*T, error] {
+	return DownloadPaged[T](url, d.options(o)...)
+}
+
+func GetPages[T any](d *Downloader, url string, next func(page *T) (nextURL string, ok bool), o ...DownloadOption) iter.Seq2[*T, error] {
+	return DownloadPages(url, next, d.options(o)...)
+}
+
+func GetGraphQL[T any](d *Downloader, url, query string, variables map[string]any, o ...DownloadOption) (*T, error) {
+	return DownloadGraphQL[T](url, query, variables, d.options(o)...)
+}
@@ -0,0 +1,62 @@
+package dlutil
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+func DownloadFile(url, path string, o ...DownloadOption) (int64, error) {
+	body, err := Download(url, o...)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	return writeFileAtomic(path, body)
+}
+
+func writeFileAtomic(path string, r io.Reader) (int64, error) {
+	dir, name := filepath.Split(path)
+	if len(dir) == 0 {
+		dir = "."
+	}
+	tmp, err := createTemp(dir, name)
+	if err != nil {
+		return 0, err
+	}
+	tmpName := tmp.Name()
+	if fi, err := os.Stat(path); err == nil {
+		tmp.Chmod(fi.Mode().Perm())
+	}
+
+	n, err := io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return n, err
+	}
+	return n, nil
+}
+
+func createTemp(dir, name string) (*os.File, error) {
+	for {
+		tmpName := filepath.Join(dir, "."+name+"."+strconv.FormatUint(rand.Uint64(), 36)+".tmp")
+		f, err := os.OpenFile(tmpName, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o666)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+}
@@ -0,0 +1,45 @@
+//go:build unix
+
+package dlutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestDownloadFileMode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer srv.Close()
+
+	umask := syscall.Umask(0o022)
+	defer syscall.Umask(umask)
+	dir := t.TempDir()
+
+	fresh := filepath.Join(dir, "fresh")
+	if _, err := DownloadFile(srv.URL, fresh); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(fresh); err != nil || fi.Mode().Perm() != 0o644 {
+		t.Fatalf("new file mode = %v, %v", fi.Mode(), err)
+	}
+
+	existing := filepath.Join(dir, "existing")
+	if err := os.WriteFile(existing, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(existing, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DownloadFile(srv.URL, existing); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(existing); err != nil || fi.Mode().Perm() != 0o640 {
+		t.Fatalf("existing file mode = %v, %v", fi.Mode(), err)
+	}
+}
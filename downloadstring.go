@@ -0,0 +1,44 @@
+package dlutil
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+func DownloadString(url string, o ...DownloadOption) (string, error) {
+	opts := newDownloadOptions(o)
+	body, resp, err := doDownload(url, &opts)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	r, err := decodeCharset(body, resp)
+	if err != nil {
+		return "", err
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+func decodeCharset(r io.Reader, resp *http.Response) (io.Reader, error) {
+	if resp == nil {
+		return r, nil
+	}
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return r, nil
+	}
+	label := strings.ToLower(params["charset"])
+	if len(label) == 0 || label == "utf-8" || label == "utf8" {
+		return r, nil
+	}
+	return charset.NewReaderLabel(label, r)
+}
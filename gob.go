@@ -0,0 +1,20 @@
+package dlutil
+
+import (
+	"encoding/gob"
+)
+
+func DownloadGob[T any](url string, o ...DownloadOption) (*T, error) {
+	body, err := Download(url, append(o, WithAcceptContentType("application/x-gob, application/octet-stream"))...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	decoder := gob.NewDecoder(body)
+	result := new(T)
+	if err := decoder.Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
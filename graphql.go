@@ -0,0 +1,81 @@
+package dlutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type GraphQLError struct {
+	Message    string            `json:"message"`
+	Locations  []GraphQLLocation `json:"locations,omitempty"`
+	Path       []any             `json:"path,omitempty"`
+	Extensions map[string]any    `json:"extensions,omitempty"`
+}
+
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+type graphQLResponse[T any] struct {
+	Data       *T             `json:"data"`
+	Errors     GraphQLErrors  `json:"errors"`
+	Extensions map[string]any `json:"extensions"`
+}
+
+func DownloadGraphQL[T any](url, query string, variables map[string]any, o ...DownloadOption) (*T, error) {
+	reqBody, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return nil, err
+	}
+	o = append(o,
+		WithMethod(http.MethodPost),
+		WithBody(bytes.NewReader(reqBody), "application/json"),
+		WithHeader("Accept", "application/graphql-response+json, application/json"),
+		WithAcceptContentType("application/graphql-response+json, application/json"))
+
+	opts := newDownloadOptions(o)
+	if opts.GenError == nil && opts.JSONErrorDecoder == nil {
+		opts.JSONErrorDecoder = decodeGraphQLError
+	}
+	body, _, err := doDownload(url, &opts)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp graphQLResponse[T]
+	if err := newJSONDecoder(body, &opts).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		return resp.Data, resp.Errors
+	}
+	return resp.Data, nil
+}
+
+func decodeGraphQLError(decoder *json.Decoder, code int) error {
+	var resp graphQLResponse[json.RawMessage]
+	if err := decoder.Decode(&resp); err != nil || len(resp.Errors) == 0 {
+		return BadStatus(code)
+	}
+	return errors.Join(BadStatus(code), resp.Errors)
+}
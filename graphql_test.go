@@ -0,0 +1,60 @@
+package dlutil
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDownloadGraphQL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.Method != http.MethodPost {
+			t.Errorf("bad request: %v %v", r.Method, err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch req.Query {
+		case "{ user }":
+			w.Write([]byte(`{"data": {"name": "` + req.Variables["name"].(string) + `"}}`))
+		case "{ partial }":
+			w.Write([]byte(`{"data": {"name": "x"}, "errors": [{"message": "field failed", "path": ["partial"]}]}`))
+		case "{ invalid }":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors": [{"message": "syntax error", "locations": [{"line": 1, "column": 3}]}]}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message": "oops"}`))
+		}
+	}))
+	defer srv.Close()
+
+	type user struct {
+		Name string `json:"name"`
+	}
+	got, err := DownloadGraphQL[user](srv.URL, "{ user }", map[string]any{"name": "alice"})
+	if err != nil || got.Name != "alice" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+
+	var gqlErrs GraphQLErrors
+	got, err = DownloadGraphQL[user](srv.URL, "{ partial }", nil)
+	if !errors.As(err, &gqlErrs) || got == nil || got.Name != "x" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+
+	var statusErr *BadStatusError
+	_, err = DownloadGraphQL[user](srv.URL, "{ invalid }", nil)
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %v", err)
+	}
+	if !errors.As(err, &gqlErrs) || gqlErrs[0].Message != "syntax error" || gqlErrs[0].Locations[0].Column != 3 {
+		t.Fatalf("expected GraphQL errors, got %v", err)
+	}
+
+	_, err = DownloadGraphQL[user](srv.URL, "{ other }", nil)
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError || errors.As(err, &gqlErrs) {
+		t.Fatalf("expected plain 500, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package dlutil
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type HealthState int
+
+const (
+	Healthy HealthState = iota
+	Degraded
+	Unhealthy
+)
+
+func (s HealthState) String() string {
+	switch s {
+	case Healthy:
+		return "healthy"
+	case Degraded:
+		return "degraded"
+	default:
+		return "unhealthy"
+	}
+}
+
+type HealthStatus struct {
+	URL        string
+	State      HealthState
+	StatusCode int
+	Latency    time.Duration
+	Detail     string
+}
+
+const maxHealthBodySize = 64 << 10
+
+func CheckHealth(url string, o ...DownloadOption) (*HealthStatus, error) {
+	o = append([]DownloadOption{WithTimeout(5 * time.Second), WithAttemptTimeout(2 * time.Second)}, o...)
+	o = append(o, WithIgnoreStatusCode())
+
+	status := &HealthStatus{URL: url, State: Unhealthy}
+	start := time.Now()
+	resp, err := DownloadWithResponse(url, o...)
+	if err != nil {
+		status.Latency = time.Since(start)
+		status.Detail = err.Error()
+		return status, err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthBodySize))
+	status.Latency = time.Since(start)
+	status.URL = resp.URL
+	status.StatusCode = resp.StatusCode
+	if err != nil {
+		status.Detail = err.Error()
+		return status, err
+	}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		status.State = Healthy
+	case resp.StatusCode == http.StatusTooManyRequests:
+		status.State = Degraded
+	default:
+		status.State = Unhealthy
+	}
+	if detail, ok := parseHealthBody(content); ok {
+		status.Detail = detail
+		switch {
+		case slices.Contains([]string{"ok", "up", "pass", "healthy", "green"}, detail):
+		case slices.Contains([]string{"warn", "degraded", "yellow"}, detail):
+			status.State = max(status.State, Degraded)
+		default:
+			status.State = Unhealthy
+		}
+	}
+	return status, nil
+}
+
+func parseHealthBody(content []byte) (string, bool) {
+	var body map[string]any
+	if err := json.Unmarshal(content, &body); err != nil {
+		return "", false
+	}
+	for _, key := range []string{"status", "health", "state"} {
+		if value, ok := body[key].(string); ok {
+			return strings.ToLower(value), true
+		}
+	}
+	return "", false
+}
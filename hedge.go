@@ -0,0 +1,104 @@
+package dlutil
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+func WithHedging(delay time.Duration, maxExtra int) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.HedgeDelay = delay
+		do.HedgeMaxExtra = maxExtra
+	}
+}
+
+type hedgeResult struct {
+	index int
+	resp  *http.Response
+	err   error
+}
+
+func canHedge(req *http.Request, opts *DownloadOptions) bool {
+	if opts.HedgeDelay <= 0 || opts.HedgeMaxExtra <= 0 {
+		return false
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func sendHedged(req *http.Request, opts *DownloadOptions) (*http.Response, error) {
+	if !canHedge(req, opts) {
+		return send(req, opts)
+	}
+
+	results := make(chan hedgeResult, opts.HedgeMaxExtra+1)
+	var cancels []context.CancelFunc
+	launch := func() error {
+		ctx, cancel := context.WithCancel(req.Context())
+		r := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return err
+			}
+			r.Body = body
+		}
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := send(r, opts)
+			results <- hedgeResult{index: index, resp: resp, err: err}
+		}()
+		return nil
+	}
+	if err := launch(); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(opts.HedgeDelay)
+	defer timer.Stop()
+	pending := 1
+	var lastErr error
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) <= opts.HedgeMaxExtra && launch() == nil {
+				pending++
+				timer.Reset(opts.HedgeDelay)
+			}
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				for i, cancel := range cancels {
+					if i != result.index {
+						cancel()
+					}
+				}
+				go discardHedges(results, pending)
+				result.resp.Body = closeWith(result.resp.Body, cancels[result.index])
+				return result.resp, nil
+			}
+			cancels[result.index]()
+			lastErr = result.err
+			if pending == 0 {
+				if req.Context().Err() != nil || len(cancels) > opts.HedgeMaxExtra || launch() != nil {
+					return nil, lastErr
+				}
+				pending++
+				timer.Reset(opts.HedgeDelay)
+			}
+		}
+	}
+}
+
+func discardHedges(results <-chan hedgeResult, pending int) {
+	for ; pending > 0; pending-- {
+		if result := <-results; result.resp != nil {
+			result.resp.Body.Close()
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package dlutil
+
+import (
+	"net/http"
+	"slices"
+)
+
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+func WithMiddleware(m ...Middleware) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.Middlewares = append(do.Middlewares, m...)
+	}
+}
+
+func OnRequest(hook func(req *http.Request) error) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.RequestHooks = append(do.RequestHooks, hook)
+	}
+}
+
+func OnResponse(hook func(resp *http.Response) error) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.ResponseHooks = append(do.ResponseHooks, hook)
+	}
+}
+
+func OnError(hook func(req *http.Request, err error)) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.ErrorHooks = append(do.ErrorHooks, hook)
+	}
+}
+
+func (d *Downloader) Use(m ...Middleware) *Downloader {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.opts = append(slices.Clip(d.opts), WithMiddleware(m...))
+	return d
+}
+
+func runHooks(next RoundTripFunc, opts *DownloadOptions) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		for _, hook := range opts.RequestHooks {
+			if err := hook(req); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := next(req)
+		if err != nil {
+			for _, hook := range opts.ErrorHooks {
+				hook(req, err)
+			}
+			return nil, err
+		}
+		for _, hook := range opts.ResponseHooks {
+			if err := hook(resp); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+		}
+		return resp, nil
+	}
+}
@@ -0,0 +1,33 @@
+package dlutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestDownloaderUseConcurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	d := NewDownloader()
+	passthrough := func(next RoundTripFunc) RoundTripFunc { return next }
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			d.Use(passthrough)
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := d.GetBytes(srv.URL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
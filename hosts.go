@@ -0,0 +1,58 @@
+package dlutil
+
+import (
+	"net/url"
+	"strings"
+)
+
+type BlockedHostError struct {
+	Host string
+}
+
+func (e BlockedHostError) Error() string {
+	return "host not allowed: " + e.Host
+}
+
+func WithAllowedHosts(patterns ...string) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.AllowedHosts = append(do.AllowedHosts, patterns...)
+	}
+}
+
+func WithBlockedHosts(patterns ...string) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.BlockedHosts = append(do.BlockedHosts, patterns...)
+	}
+}
+
+func checkHost(u *url.URL, opts *DownloadOptions) error {
+	if len(opts.AllowedHosts) == 0 && len(opts.BlockedHosts) == 0 {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	for _, pattern := range opts.BlockedHosts {
+		if matchHost(pattern, host) {
+			return &BlockedHostError{Host: host}
+		}
+	}
+	if len(opts.AllowedHosts) == 0 {
+		return nil
+	}
+	for _, pattern := range opts.AllowedHosts {
+		if matchHost(pattern, host) {
+			return nil
+		}
+	}
+	return &BlockedHostError{Host: host}
+}
+
+func matchHost(pattern, host string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	if pattern == "*" {
+		return true
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
@@ -0,0 +1,25 @@
+package dlutil
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+func DownloadHTML(url string, o ...DownloadOption) (*html.Node, error) {
+	opts := newDownloadOptions(append(o, WithAcceptContentType("text/html, application/xhtml+xml")))
+	body, resp, err := doDownload(url, &opts)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var contentType string
+	if resp != nil {
+		contentType = resp.Header.Get("Content-Type")
+	}
+	r, err := charset.NewReader(body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return html.Parse(r)
+}
@@ -0,0 +1,150 @@
+package dlutil
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+const http3BrokenTTL = 5 * time.Minute
+
+func WithHTTP3() DownloadOption {
+	return func(do *DownloadOptions) {
+		do.HTTP3 = true
+	}
+}
+
+type http3Transport struct {
+	h3       *http3.Transport
+	fallback *http.Transport
+	mu       sync.Mutex
+	broken   map[string]time.Time
+}
+
+type http3DialError struct {
+	err error
+}
+
+func (e *http3DialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *http3DialError) Unwrap() error {
+	return e.err
+}
+
+func newHTTP3Transport(fallback *http.Transport, opts *DownloadOptions) *http3Transport {
+	h3 := &http3.Transport{}
+	if fallback.TLSClientConfig != nil {
+		h3.TLSClientConfig = fallback.TLSClientConfig.Clone()
+	}
+	dial := quic.DialAddrEarly
+	if needsResolvingDialer(opts) {
+		lookup := newLookup(opts)
+		ssrf := opts.SSRFProtection
+		dial = func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			ips, err := lookup(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			if ssrf {
+				if err := checkAddrs(host, ips); err != nil {
+					return nil, err
+				}
+			}
+			err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+			for _, ip := range ips {
+				conn, dialErr := quic.DialAddrEarly(ctx, net.JoinHostPort(ip.Unmap().String(), port), tlsCfg, cfg)
+				if dialErr == nil {
+					return conn, nil
+				}
+				err = dialErr
+			}
+			return nil, err
+		}
+	}
+	h3.Dial = func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+		conn, err := dial(ctx, addr, tlsCfg, cfg)
+		if err != nil {
+			return nil, &http3DialError{err: err}
+		}
+		return conn, nil
+	}
+	return &http3Transport{
+		h3:       h3,
+		fallback: fallback,
+		broken:   make(map[string]time.Time),
+	}
+}
+
+func (t *http3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" || t.proxied(req) || t.isBroken(req.URL.Host) {
+		return t.fallback.RoundTrip(req)
+	}
+	resp, err := t.h3.RoundTrip(req)
+	if err == nil || req.Context().Err() != nil {
+		return resp, err
+	}
+	var blocked *BlockedAddressError
+	if errors.As(err, &blocked) {
+		return nil, err
+	}
+	var dialErr *http3DialError
+	if !errors.As(err, &dialErr) && !isIdempotent(req) {
+		return nil, err
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, err
+		}
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	t.markBroken(req.URL.Host)
+	return t.fallback.RoundTrip(req)
+}
+
+func (t *http3Transport) CloseIdleConnections() {
+	t.h3.Close()
+	t.fallback.CloseIdleConnections()
+}
+
+func (t *http3Transport) proxied(req *http.Request) bool {
+	if t.fallback.Proxy == nil {
+		return false
+	}
+	proxyURL, err := t.fallback.Proxy(req)
+	return err != nil || proxyURL != nil
+}
+
+func (t *http3Transport) isBroken(host string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.broken[host]
+	if ok && !time.Now().Before(until) {
+		delete(t.broken, host)
+		return false
+	}
+	return ok
+}
+
+func (t *http3Transport) markBroken(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.broken[host] = time.Now().Add(http3BrokenTTL)
+}
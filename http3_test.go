@@ -0,0 +1,123 @@
+package dlutil
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestHTTP3SkippedWithProxies(t *testing.T) {
+	target, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	url := "https://" + target.LocalAddr().String() + "/"
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+
+	options := map[string]DownloadOption{
+		"http proxy":  WithProxy(proxy.URL),
+		"socks5":      WithSOCKS5("127.0.0.1:1", nil),
+		"unix socket": WithUnixSocket(filepath.Join(t.TempDir(), "missing.sock")),
+	}
+	for name, option := range options {
+		t.Run(name, func(t *testing.T) {
+			DownloadBytes(url, WithClient(&http.Client{Transport: &http.Transport{}}), WithHTTP3(), option, WithTimeout(time.Second))
+			target.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+			if _, addr, err := target.ReadFrom(make([]byte, 2048)); err == nil {
+				t.Fatalf("QUIC packet sent directly from %v", addr)
+			}
+		})
+	}
+}
+
+func TestHTTP3FallbackOnDialError(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer srv.Close()
+	sink, err := net.ListenPacket("udp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Skip(err)
+	}
+	defer sink.Close()
+
+	tr := newHTTP3Transport(srv.Client().Transport.(*http.Transport), &DownloadOptions{})
+	tr.h3.QUICConfig = &quic.Config{HandshakeIdleTimeout: 100 * time.Millisecond}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "payload" {
+		t.Fatalf("got %q", body)
+	}
+
+	host := req.URL.Host
+	if !tr.isBroken(host) {
+		t.Fatal("expected host to be marked broken")
+	}
+	tr.broken[host] = time.Now().Add(-time.Second)
+	if tr.isBroken(host) {
+		t.Fatal("expected broken mark to expire")
+	}
+}
+
+func TestHTTP3FallbackAfterRequestSent(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tcp"))
+	}))
+	defer srv.Close()
+	conn, err := net.ListenPacket("udp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Skip(err)
+	}
+	h3srv := &http3.Server{
+		TLSConfig: http3.ConfigureTLSConfig(srv.TLS.Clone()),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}),
+	}
+	go h3srv.Serve(conn)
+	defer h3srv.Close()
+
+	tr := newHTTP3Transport(srv.Client().Transport.(*http.Transport), &DownloadOptions{})
+	for method, fallback := range map[string]bool{http.MethodGet: true, http.MethodPost: false} {
+		t.Run(method, func(t *testing.T) {
+			req, _ := http.NewRequest(method, srv.URL, nil)
+			resp, err := tr.RoundTrip(req)
+			if !fallback {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("expected the HTTP/3 error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "tcp" {
+				t.Fatalf("got %q", body)
+			}
+			tr.mu.Lock()
+			clear(tr.broken)
+			tr.mu.Unlock()
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package dlutil
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func WithHTTPCache() DownloadOption {
+	return func(do *DownloadOptions) {
+		do.HTTPCache = true
+	}
+}
+
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if len(name) > 0 {
+				directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return directives
+}
+
+func httpFreshness(header http.Header) (freshness time.Duration, explicit, store bool) {
+	cc := parseCacheControl(header)
+	if _, ok := cc["no-store"]; ok {
+		return 0, true, false
+	}
+	if _, ok := cc["private"]; ok {
+		return 0, true, false
+	}
+	if _, ok := cc["no-cache"]; ok {
+		return 0, true, true
+	}
+
+	var age time.Duration
+	if seconds, err := strconv.ParseInt(header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		age = time.Duration(seconds) * time.Second
+	}
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if arg, ok := cc[directive]; ok {
+			seconds, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return 0, true, true
+			}
+			return time.Duration(seconds)*time.Second - age, true, true
+		}
+	}
+
+	if expiresHeader := header.Get("Expires"); len(expiresHeader) > 0 {
+		expires, err := http.ParseTime(expiresHeader)
+		if err != nil {
+			return 0, true, true
+		}
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = time.Now()
+		}
+		return expires.Sub(date) - age, true, true
+	}
+	return 0, false, true
+}
@@ -0,0 +1,32 @@
+package dlutil
+
+import (
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	_ "golang.org/x/image/webp"
+)
+
+func DownloadImage(url string, o ...DownloadOption) (image.Image, string, error) {
+	body, err := Download(url, append(o, WithAcceptContentType("image/*"))...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer body.Close()
+
+	return image.Decode(body)
+}
+
+func DownloadImageConfig(url string, o ...DownloadOption) (image.Config, string, error) {
+	opts := probeOptions(append(o, WithAcceptContentType("image/*")), http.MethodGet)
+	body, _, err := doDownload(url, &opts)
+	if err != nil {
+		return image.Config{}, "", err
+	}
+	defer body.Close()
+
+	return image.DecodeConfig(body)
+}
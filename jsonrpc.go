@@ -0,0 +1,78 @@
+package dlutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+)
+
+var jsonRPCID atomic.Uint64
+
+type JSONRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e JSONRPCError) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+type jsonRPCRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+	ID      uint64 `json:"id"`
+}
+
+type jsonRPCResponse[T any] struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Result  *T            `json:"result"`
+	Error   *JSONRPCError `json:"error"`
+	ID      *uint64       `json:"id"`
+}
+
+func CallJSONRPC[T any](url, method string, params any, o ...DownloadOption) (*T, error) {
+	id := jsonRPCID.Add(1)
+	reqBody, err := json.Marshal(jsonRPCRequest{JSONRPC: "2.0", Method: method, Params: params, ID: id})
+	if err != nil {
+		return nil, err
+	}
+	o = append(o,
+		WithMethod(http.MethodPost),
+		WithBody(bytes.NewReader(reqBody), "application/json"),
+		WithAcceptContentType("application/json"))
+
+	opts := newDownloadOptions(o)
+	body, _, err := doDownload(url, &opts)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp jsonRPCResponse[T]
+	if err := newJSONDecoder(body, &opts).Decode(&resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	if resp.ID == nil || *resp.ID != id {
+		return nil, errors.New("jsonrpc response id mismatch")
+	}
+	if resp.Result == nil {
+		resp.Result = new(T)
+	}
+	return resp.Result, nil
+}
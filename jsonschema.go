@@ -0,0 +1,75 @@
+package dlutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+type SchemaViolation struct {
+	InstanceLocation string
+	KeywordLocation  string
+	Message          string
+}
+
+type SchemaValidationError struct {
+	Violations []SchemaViolation
+}
+
+func (e SchemaValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = fmt.Sprintf("%s: %s", v.InstanceLocation, v.Message)
+	}
+	return "json schema validation failed: " + strings.Join(messages, "; ")
+}
+
+func WithJSONSchema(schema []byte) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.JSONSchema = schema
+	}
+}
+
+func validateJSONSchema(schema, content []byte) error {
+	const schemaURL = "schema.json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(schema)); err != nil {
+		return err
+	}
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var instance any
+	if err := decoder.Decode(&instance); err != nil {
+		return err
+	}
+
+	err = compiled.Validate(instance)
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		return &SchemaValidationError{Violations: collectSchemaViolations(validationErr, nil)}
+	}
+	return err
+}
+
+func collectSchemaViolations(err *jsonschema.ValidationError, violations []SchemaViolation) []SchemaViolation {
+	if len(err.Causes) == 0 {
+		return append(violations, SchemaViolation{
+			InstanceLocation: err.InstanceLocation,
+			KeywordLocation:  err.KeywordLocation,
+			Message:          err.Message,
+		})
+	}
+	for _, cause := range err.Causes {
+		violations = collectSchemaViolations(cause, violations)
+	}
+	return violations
+}
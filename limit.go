@@ -0,0 +1,136 @@
+package dlutil
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+func (tb *tokenBucket) reserve(n float64) time.Duration {
+	if tb.rate <= 0 {
+		return 0
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+	tb.tokens = min(tb.burst, tb.tokens+now.Sub(tb.last).Seconds()*tb.rate)
+	tb.last = now
+	tb.tokens -= n
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+func (tb *tokenBucket) wait(ctx context.Context, n float64) error {
+	if delay := tb.reserve(n); delay > 0 {
+		return sleepContext(ctx, delay)
+	}
+	return nil
+}
+
+type BandwidthLimiter struct {
+	bucket *tokenBucket
+}
+
+func NewBandwidthLimiter(bytesPerSec int64) *BandwidthLimiter {
+	return &BandwidthLimiter{
+		bucket: newTokenBucket(float64(bytesPerSec), float64(bytesPerSec)),
+	}
+}
+
+func (l *BandwidthLimiter) WaitN(ctx context.Context, n int) error {
+	return l.bucket.wait(ctx, float64(n))
+}
+
+func WithBandwidthLimit(bytesPerSec int64) DownloadOption {
+	return WithBandwidthLimiter(NewBandwidthLimiter(bytesPerSec))
+}
+
+func WithBandwidthLimiter(limiter *BandwidthLimiter) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.BandwidthLimiter = limiter
+	}
+}
+
+type rateLimitedReader struct {
+	ctx     context.Context
+	r       io.ReadCloser
+	limiter *BandwidthLimiter
+}
+
+func newRateLimitedReader(ctx context.Context, r io.ReadCloser, limiter *BandwidthLimiter) io.ReadCloser {
+	return &rateLimitedReader{ctx: ctx, r: r, limiter: limiter}
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if burst := int(r.limiter.bucket.burst); burst > 0 && len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func (r *rateLimitedReader) Close() error {
+	return r.r.Close()
+}
+
+type Limiter struct {
+	bandwidth *BandwidthLimiter
+	requests  *tokenBucket
+}
+
+func NewLimiter(bytesPerSec int64, requestsPerSec float64) *Limiter {
+	l := new(Limiter)
+	if bytesPerSec > 0 {
+		l.bandwidth = NewBandwidthLimiter(bytesPerSec)
+	}
+	if requestsPerSec > 0 {
+		l.requests = newTokenBucket(requestsPerSec, max(1, requestsPerSec))
+	}
+	return l
+}
+
+func (l *Limiter) WaitRequest(ctx context.Context) error {
+	if l.requests == nil {
+		return nil
+	}
+	return l.requests.wait(ctx, 1)
+}
+
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l.bandwidth == nil {
+		return nil
+	}
+	return l.bandwidth.WaitN(ctx, n)
+}
+
+func WithLimiter(limiter *Limiter) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.Limiter = limiter
+	}
+}
@@ -0,0 +1,38 @@
+package dlutil
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBandwidthLimitNonPositive(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 64<<10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	for _, rate := range []int64{0, -1} {
+		got, err := DownloadBytes(srv.URL, WithBandwidthLimit(rate), WithTimeout(5*time.Second))
+		if err != nil {
+			t.Fatalf("rate %d: %v", rate, err)
+		}
+		if len(got) != len(data) {
+			t.Fatalf("rate %d: got %d bytes", rate, len(got))
+		}
+	}
+}
+
+func TestTokenBucketUnlimited(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		tb := newTokenBucket(rate, rate)
+		for range 3 {
+			if delay := tb.reserve(1 << 20); delay != 0 {
+				t.Fatalf("rate %v: delay %v", rate, delay)
+			}
+		}
+	}
+}
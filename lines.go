@@ -0,0 +1,37 @@
+package dlutil
+
+import (
+	"bufio"
+	"iter"
+)
+
+func WithMaxLineLength(n int) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.MaxLineLength = n
+	}
+}
+
+func DownloadLines(url string, o ...DownloadOption) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		opts := newDownloadOptions(o)
+		body, _, err := doDownload(url, &opts)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		defer body.Close()
+
+		scanner := bufio.NewScanner(body)
+		if opts.MaxLineLength > 0 {
+			scanner.Buffer(make([]byte, 0, min(opts.MaxLineLength, 64*1024)), opts.MaxLineLength)
+		}
+		for scanner.Scan() {
+			if !yield(scanner.Text(), nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield("", err)
+		}
+	}
+}
@@ -0,0 +1,551 @@
+package dlutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/razzie/razcache"
+)
+
+var (
+	ErrJobNotFound     = errors.New("job not found")
+	ErrInvalidJobState = errors.New("invalid job state")
+	ErrManagerClosed   = errors.New("manager closed")
+	ErrJobOptions      = errors.New("per-job options cannot be persisted")
+)
+
+type JobState int
+
+const (
+	JobQueued JobState = iota
+	JobRunning
+	JobPaused
+	JobDone
+	JobFailed
+	JobCanceled
+)
+
+func (s JobState) String() string {
+	switch s {
+	case JobQueued:
+		return "queued"
+	case JobRunning:
+		return "running"
+	case JobPaused:
+		return "paused"
+	case JobDone:
+		return "done"
+	case JobFailed:
+		return "failed"
+	default:
+		return "canceled"
+	}
+}
+
+func (s JobState) terminal() bool {
+	return s == JobDone || s == JobFailed || s == JobCanceled
+}
+
+type JobProgress struct {
+	ID         string
+	URL        string
+	Path       string
+	State      JobState
+	Priority   int
+	Downloaded int64
+	Total      int64
+	Err        error
+}
+
+type Manager struct {
+	opts    []DownloadOption
+	workers int
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	cond    *sync.Cond
+	closed  bool
+	preempt bool
+	store   razcache.Cache
+	key     string
+	jobs    map[string]*job
+	order   []*job
+	queue   []*job
+}
+
+type job struct {
+	id         string
+	url        string
+	path       string
+	opts       []DownloadOption
+	state      JobState
+	priority   int
+	preempted  bool
+	active     bool
+	downloaded int64
+	total      int64
+	err        error
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+func NewManager(workers int, o ...DownloadOption) *Manager {
+	m := newManager(workers, o)
+	m.start()
+	return m
+}
+
+func newManager(workers int, o []DownloadOption) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		opts:    o,
+		workers: max(workers, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    make(map[string]*job),
+	}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+func (m *Manager) start() {
+	for range m.workers {
+		m.wg.Add(1)
+		go m.worker()
+	}
+}
+
+func (m *Manager) SetPreemption(preempt bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.preempt = preempt
+}
+
+func (m *Manager) Enqueue(url, path string, o ...DownloadOption) (string, error) {
+	return m.EnqueuePriority(url, path, 0, o...)
+}
+
+func (m *Manager) EnqueuePriority(url, path string, priority int, o ...DownloadOption) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return "", ErrManagerClosed
+	}
+	if m.store != nil && len(o) > 0 {
+		return "", ErrJobOptions
+	}
+	j := &job{
+		id:       newJobID(),
+		url:      url,
+		path:     path,
+		opts:     o,
+		state:    JobQueued,
+		priority: priority,
+		total:    -1,
+		done:     make(chan struct{}),
+	}
+	m.jobs[j.id] = j
+	m.order = append(m.order, j)
+	m.push(j)
+	return j.id, m.save()
+}
+
+func (m *Manager) SetPriority(id string, priority int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if j.state.terminal() {
+		return ErrInvalidJobState
+	}
+	j.priority = priority
+	if j.state == JobQueued && !j.active {
+		m.dequeue(j)
+		m.push(j)
+	}
+	return m.save()
+}
+
+func (m *Manager) Pause(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	switch j.state {
+	case JobQueued:
+		m.dequeue(j)
+	case JobRunning:
+		j.cancel()
+	default:
+		return ErrInvalidJobState
+	}
+	j.state = JobPaused
+	return m.save()
+}
+
+func (m *Manager) Resume(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if j.state != JobPaused {
+		return ErrInvalidJobState
+	}
+	j.state = JobQueued
+	j.err = nil
+	if !j.active {
+		m.push(j)
+	}
+	return m.save()
+}
+
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	switch j.state {
+	case JobQueued, JobPaused:
+		m.dequeue(j)
+		removePart(j.path)
+		m.finish(j, JobCanceled, context.Canceled)
+	case JobRunning:
+		j.state = JobCanceled
+		j.cancel()
+	default:
+		return ErrInvalidJobState
+	}
+	return m.save()
+}
+
+func (m *Manager) Progress(id string) (JobProgress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return JobProgress{}, ErrJobNotFound
+	}
+	return j.progress(), nil
+}
+
+func (m *Manager) Jobs() []JobProgress {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]JobProgress, len(m.order))
+	for i, j := range m.order {
+		jobs[i] = j.progress()
+	}
+	return jobs
+}
+
+func (m *Manager) Wait(ctx context.Context, id string) (JobProgress, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return JobProgress{}, ErrJobNotFound
+	}
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		return JobProgress{}, ctx.Err()
+	}
+	return m.Progress(id)
+}
+
+func (m *Manager) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if !j.state.terminal() {
+		return ErrInvalidJobState
+	}
+	delete(m.jobs, id)
+	m.order = slices.DeleteFunc(m.order, func(other *job) bool { return other == j })
+	return m.save()
+}
+
+func (m *Manager) Close() {
+	m.mu.Lock()
+	m.closed = true
+	m.cond.Broadcast()
+	m.mu.Unlock()
+	m.cancel()
+	m.wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.save()
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for {
+		m.mu.Lock()
+		for !m.closed && len(m.queue) == 0 {
+			m.cond.Wait()
+		}
+		if m.closed {
+			m.mu.Unlock()
+			return
+		}
+		j := m.queue[0]
+		m.queue = m.queue[1:]
+		ctx, cancel := context.WithCancel(m.ctx)
+		j.state = JobRunning
+		j.active = true
+		j.cancel = cancel
+		m.mu.Unlock()
+
+		err := m.run(ctx, j)
+		cancel()
+
+		m.mu.Lock()
+		preempted := j.preempted
+		j.preempted = false
+		j.active = false
+		switch {
+		case err != nil && j.state == JobQueued:
+			if m.ctx.Err() == nil {
+				m.push(j)
+			}
+		case err != nil && j.state == JobPaused:
+		case err != nil && j.state == JobCanceled:
+			removePart(j.path)
+			m.finish(j, JobCanceled, context.Canceled)
+		case err != nil && m.ctx.Err() != nil:
+			j.state = JobQueued
+		case err != nil && preempted:
+			j.state = JobQueued
+			m.insert(j, true)
+		case err != nil:
+			m.finish(j, JobFailed, err)
+		default:
+			m.finish(j, JobDone, nil)
+		}
+		m.save()
+		m.mu.Unlock()
+	}
+}
+
+func (m *Manager) run(ctx context.Context, j *job) error {
+	part := partPath(j.path)
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	validator, _ := os.ReadFile(validatorPath(j.path))
+	if offset > 0 && len(validator) == 0 {
+		if offset, err = restart(f); err != nil {
+			return err
+		}
+	}
+
+	o := append(slices.Clip(m.opts), j.opts...)
+	o = append(o, WithContext(ctx))
+	if offset > 0 {
+		o = append(o, WithHeader("Range", fmt.Sprintf("bytes=%d-", offset)), WithHeader("If-Range", string(validator)))
+	}
+	resp, err := DownloadWithResponse(j.url, o...)
+	var badStatus *BadStatusError
+	if offset > 0 && errors.As(err, &badStatus) && badStatus.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		m.setProgress(j, offset, offset)
+		return m.complete(f, part, j.path)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		if offset, err = restart(f); err != nil {
+			return err
+		}
+	}
+	if offset == 0 {
+		if validator := ifRangeValidator(resp.Header); len(validator) > 0 {
+			if err := os.WriteFile(validatorPath(j.path), []byte(validator), 0o644); err != nil {
+				return err
+			}
+		} else {
+			os.Remove(validatorPath(j.path))
+		}
+	}
+	m.setProgress(j, offset, responseTotal(resp, offset))
+
+	if _, err := io.Copy(&jobWriter{w: f, m: m, j: j}, resp.Body); err != nil {
+		return err
+	}
+	return m.complete(f, part, j.path)
+}
+
+func (m *Manager) complete(f *os.File, part, path string) error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(part, path); err != nil {
+		return err
+	}
+	os.Remove(validatorPath(path))
+	return nil
+}
+
+func restart(f *os.File) (int64, error) {
+	if err := f.Truncate(0); err != nil {
+		return 0, err
+	}
+	return f.Seek(0, io.SeekStart)
+}
+
+func ifRangeValidator(header http.Header) string {
+	if etag := header.Get("ETag"); len(etag) > 0 && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+func (m *Manager) setProgress(j *job, downloaded, total int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.downloaded = downloaded
+	j.total = total
+}
+
+func (m *Manager) finish(j *job, state JobState, err error) {
+	select {
+	case <-j.done:
+		return
+	default:
+	}
+	j.state = state
+	j.err = err
+	close(j.done)
+}
+
+func (m *Manager) push(j *job) {
+	m.insert(j, false)
+}
+
+func (m *Manager) insert(j *job, front bool) {
+	i := len(m.queue)
+	for i > 0 && (m.queue[i-1].priority < j.priority || front && m.queue[i-1].priority == j.priority) {
+		i--
+	}
+	m.queue = slices.Insert(m.queue, i, j)
+	m.cond.Signal()
+	if m.preempt {
+		m.preemptFor(j)
+	}
+}
+
+func (m *Manager) preemptFor(j *job) {
+	var running int
+	var victim *job
+	for _, other := range m.order {
+		if other.state != JobRunning || other.preempted {
+			continue
+		}
+		running++
+		if other.priority < j.priority && (victim == nil || other.priority < victim.priority) {
+			victim = other
+		}
+	}
+	if running >= m.workers && victim != nil {
+		victim.preempted = true
+		victim.cancel()
+	}
+}
+
+func (m *Manager) dequeue(j *job) {
+	m.queue = slices.DeleteFunc(m.queue, func(other *job) bool { return other == j })
+}
+
+func (j *job) progress() JobProgress {
+	return JobProgress{
+		ID:         j.id,
+		URL:        j.url,
+		Path:       j.path,
+		State:      j.state,
+		Priority:   j.priority,
+		Downloaded: j.downloaded,
+		Total:      j.total,
+		Err:        j.err,
+	}
+}
+
+type jobWriter struct {
+	w io.Writer
+	m *Manager
+	j *job
+}
+
+func (w *jobWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.m.mu.Lock()
+	w.j.downloaded += int64(n)
+	w.m.mu.Unlock()
+	return n, err
+}
+
+func responseTotal(resp *Response, offset int64) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+			if n, err := strconv.ParseInt(total, 10, 64); err == nil {
+				return n
+			}
+		}
+	}
+	if n, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		return offset + n
+	}
+	return -1
+}
+
+func partPath(path string) string {
+	return path + ".part"
+}
+
+func validatorPath(path string) string {
+	return path + ".part.validator"
+}
+
+func removePart(path string) {
+	os.Remove(partPath(path))
+	os.Remove(validatorPath(path))
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
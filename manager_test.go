@@ -0,0 +1,125 @@
+package dlutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSlowRangeServer(t *testing.T, data []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := 0
+		w.Header().Set("ETag", `"v1"`)
+		if rg := r.Header.Get("Range"); len(rg) > 0 && r.Header.Get("If-Range") == `"v1"` {
+			start, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rg, "bytes="), "-"))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(data)-1, len(data)))
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)-start))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		}
+		for i := start; i < len(data); i += 1000 {
+			w.Write(data[i:min(i+1000, len(data))])
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestManagerPauseResumeRunning(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 20000)
+	srv := newSlowRangeServer(t, data)
+	path := filepath.Join(t.TempDir(), "out")
+
+	m := NewManager(2)
+	defer m.Close()
+	id, err := m.Enqueue(srv.URL, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		time.Sleep(20 * time.Millisecond)
+		if err := m.Pause(id); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Resume(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	progress, err := m.Wait(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.State != JobDone {
+		t.Fatalf("state = %v, err = %v", progress.State, progress.Err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %d bytes, want %d", len(got), len(data))
+	}
+}
+
+func TestManagerResumeValidator(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	srv := newSlowRangeServer(t, data)
+	validators := map[string]string{
+		"changed": `"v0"`,
+		"missing": "",
+	}
+	for name, validator := range validators {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out")
+			if err := os.WriteFile(partPath(path), []byte("stale"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if len(validator) > 0 {
+				if err := os.WriteFile(validatorPath(path), []byte(validator), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			m := NewManager(1)
+			defer m.Close()
+			id, err := m.Enqueue(srv.URL, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if progress, err := m.Wait(ctx, id); err != nil || progress.State != JobDone {
+				t.Fatalf("progress = %+v, err = %v", progress, err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("got %d bytes starting %q, want %d", len(got), got[:5], len(data))
+			}
+			if _, err := os.Stat(validatorPath(path)); !os.IsNotExist(err) {
+				t.Fatalf("validator left behind: %v", err)
+			}
+		})
+	}
+}
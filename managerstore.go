@@ -0,0 +1,105 @@
+package dlutil
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/razzie/razcache"
+)
+
+type persistedJob struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	Path       string   `json:"path"`
+	State      JobState `json:"state"`
+	Priority   int      `json:"priority,omitempty"`
+	Downloaded int64    `json:"downloaded,omitempty"`
+	Total      int64    `json:"total"`
+	Err        string   `json:"err,omitempty"`
+}
+
+func NewPersistentManager(workers int, cache razcache.Cache, key string, o ...DownloadOption) (*Manager, error) {
+	m := newManager(workers, o)
+	m.store = cache
+	m.key = key
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	m.start()
+	return m, nil
+}
+
+func (m *Manager) load() error {
+	content, err := m.store.Get(m.key)
+	if errors.Is(err, razcache.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var jobs []persistedJob
+	if err := json.Unmarshal([]byte(content), &jobs); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, pj := range jobs {
+		j := &job{
+			id:         pj.ID,
+			url:        pj.URL,
+			path:       pj.Path,
+			state:      pj.State,
+			priority:   pj.Priority,
+			downloaded: pj.Downloaded,
+			total:      pj.Total,
+			done:       make(chan struct{}),
+		}
+		if len(pj.Err) > 0 {
+			j.err = errors.New(pj.Err)
+		}
+		if !j.state.terminal() {
+			if fi, err := os.Stat(partPath(j.path)); err == nil {
+				j.downloaded = fi.Size()
+			} else {
+				j.downloaded = 0
+			}
+		} else {
+			close(j.done)
+		}
+		m.jobs[j.id] = j
+		m.order = append(m.order, j)
+		if j.state == JobQueued || j.state == JobRunning {
+			j.state = JobQueued
+			m.push(j)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) save() error {
+	if m.store == nil {
+		return nil
+	}
+	jobs := make([]persistedJob, len(m.order))
+	for i, j := range m.order {
+		jobs[i] = persistedJob{
+			ID:         j.id,
+			URL:        j.url,
+			Path:       j.path,
+			State:      j.state,
+			Priority:   j.priority,
+			Downloaded: j.downloaded,
+			Total:      j.total,
+		}
+		if j.err != nil {
+			jobs[i].Err = j.err.Error()
+		}
+	}
+	content, err := json.Marshal(jobs)
+	if err != nil {
+		return err
+	}
+	return m.store.Set(m.key, string(content), 0)
+}
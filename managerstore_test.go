@@ -0,0 +1,41 @@
+package dlutil
+
+import (
+	"errors"
+	"testing"
+)
+
+var errStoreDown = errors.New("store down")
+
+type failingCache struct {
+	*testCache
+}
+
+func (c failingCache) Get(key string) (string, error) {
+	return "", errStoreDown
+}
+
+func TestPersistentManagerLoadError(t *testing.T) {
+	if _, err := NewPersistentManager(1, failingCache{newTestCache()}, "jobs"); !errors.Is(err, errStoreDown) {
+		t.Fatalf("expected store error, got %v", err)
+	}
+	m, err := NewPersistentManager(1, newTestCache(), "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+}
+
+func TestPersistentManagerRejectsJobOptions(t *testing.T) {
+	m, err := NewPersistentManager(1, newTestCache(), "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, err := m.Enqueue("http://example.com/", t.TempDir()+"/out", WithHeader("X-Job", "1")); !errors.Is(err, ErrJobOptions) {
+		t.Fatalf("expected ErrJobOptions, got %v", err)
+	}
+	if jobs := m.Jobs(); len(jobs) != 0 {
+		t.Fatalf("rejected job was queued: %+v", jobs)
+	}
+}
@@ -0,0 +1,42 @@
+package dlutil
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrTooLarge = errors.New("response too large")
+
+func WithMaxSize(n int64) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.MaxSize = n
+	}
+}
+
+type maxSizeReader struct {
+	r      io.ReadCloser
+	remain int64
+}
+
+func newMaxSizeReader(r io.ReadCloser, n int64) io.ReadCloser {
+	return &maxSizeReader{r: r, remain: n}
+}
+
+func (r *maxSizeReader) Read(p []byte) (int, error) {
+	if r.remain < 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > r.remain+1 {
+		p = p[:r.remain+1]
+	}
+	n, err := r.r.Read(p)
+	r.remain -= int64(n)
+	if r.remain < 0 {
+		return n + int(r.remain), ErrTooLarge
+	}
+	return n, err
+}
+
+func (r *maxSizeReader) Close() error {
+	return r.r.Close()
+}
@@ -0,0 +1,78 @@
+package dlutil
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type SlowDownloadError struct {
+	MinSpeed int64
+	Window   time.Duration
+	Received int64
+}
+
+func (e SlowDownloadError) Error() string {
+	return fmt.Sprintf("download too slow: received %d bytes in %s (minimum %d bytes/s)", e.Received, e.Window, e.MinSpeed)
+}
+
+func WithMinSpeed(bytesPerSec int64, window time.Duration) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.MinSpeed = bytesPerSec
+		do.MinSpeedWindow = window
+	}
+}
+
+type minSpeedReader struct {
+	r         io.ReadCloser
+	received  atomic.Int64
+	err       atomic.Pointer[SlowDownloadError]
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newMinSpeedReader(r io.ReadCloser, bytesPerSec int64, window time.Duration) io.ReadCloser {
+	msr := &minSpeedReader{
+		r:    r,
+		done: make(chan struct{}),
+	}
+	go msr.watch(int64(float64(bytesPerSec)*window.Seconds()), bytesPerSec, window)
+	return msr
+}
+
+func (msr *minSpeedReader) watch(minBytes, bytesPerSec int64, window time.Duration) {
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-msr.done:
+			return
+		case <-ticker.C:
+			if received := msr.received.Swap(0); received < minBytes {
+				msr.err.Store(&SlowDownloadError{MinSpeed: bytesPerSec, Window: window, Received: received})
+				msr.r.Close()
+				return
+			}
+		}
+	}
+}
+
+func (msr *minSpeedReader) Read(p []byte) (int, error) {
+	n, err := msr.r.Read(p)
+	msr.received.Add(int64(n))
+	if err != nil {
+		if slowErr := msr.err.Load(); slowErr != nil {
+			return n, slowErr
+		}
+	}
+	return n, err
+}
+
+func (msr *minSpeedReader) Close() error {
+	msr.closeOnce.Do(func() {
+		close(msr.done)
+	})
+	return msr.r.Close()
+}
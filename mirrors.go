@@ -0,0 +1,64 @@
+package dlutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	neturl "net/url"
+	"slices"
+)
+
+var ErrNoMirrors = errors.New("no mirrors")
+
+type MirrorStrategy func(urls []string) []string
+
+func InOrder(urls []string) []string {
+	return urls
+}
+
+func Shuffled(urls []string) []string {
+	urls = slices.Clone(urls)
+	rand.Shuffle(len(urls), func(i, j int) {
+		urls[i], urls[j] = urls[j], urls[i]
+	})
+	return urls
+}
+
+func WithMirrorStrategy(strategy MirrorStrategy) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.MirrorStrategy = strategy
+	}
+}
+
+func DownloadFromMirrors(urls []string, o ...DownloadOption) (io.ReadCloser, string, error) {
+	opts := newDownloadOptions(o)
+	if opts.MirrorStrategy != nil {
+		urls = opts.MirrorStrategy(urls)
+	}
+	err := ErrNoMirrors
+	for _, url := range urls {
+		var body io.ReadCloser
+		body, err = Download(url, o...)
+		if err == nil {
+			return body, url, nil
+		}
+		if !isMirrorFailure(err) || opts.Ctx.Err() != nil {
+			return nil, "", err
+		}
+	}
+	return nil, "", err
+}
+
+func isMirrorFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *BadStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	var urlErr *neturl.Error
+	return errors.As(err, &urlErr) || errors.Is(err, ErrAttemptTimeout)
+}
@@ -0,0 +1,186 @@
+package dlutil
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultProbeBytes = 64 << 10
+
+type MirrorSet struct {
+	mirrors   []string
+	probePath string
+	opts      []DownloadOption
+	mu        sync.Mutex
+	stats     map[string]MirrorStats
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+type MirrorStats struct {
+	URL     string
+	Healthy bool
+	Elapsed time.Duration
+	Bytes   int64
+	Probed  time.Time
+	Err     error
+}
+
+func (s MirrorStats) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
+
+func NewMirrorSet(mirrors []string, probePath string, interval time.Duration, o ...DownloadOption) *MirrorSet {
+	m := &MirrorSet{
+		mirrors:   slices.Clone(mirrors),
+		probePath: probePath,
+		opts:      o,
+		stats:     make(map[string]MirrorStats),
+	}
+	if interval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.cancel = cancel
+		m.done = make(chan struct{})
+		go m.run(ctx, interval)
+	}
+	return m
+}
+
+func (m *MirrorSet) run(ctx context.Context, interval time.Duration) {
+	defer close(m.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.Probe(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *MirrorSet) Close() {
+	if m.cancel != nil {
+		m.cancel()
+		<-m.done
+	}
+}
+
+func (m *MirrorSet) Probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, mirror := range m.mirrors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats := m.probe(ctx, mirror)
+			if ctx.Err() != nil {
+				return
+			}
+			m.mu.Lock()
+			m.stats[mirror] = stats
+			m.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+func (m *MirrorSet) probe(ctx context.Context, mirror string) MirrorStats {
+	stats := MirrorStats{URL: mirror, Probed: time.Now()}
+	o := m.options([]DownloadOption{WithContext(ctx)})
+	if len(m.probePath) > 0 {
+		o = append(o, WithHeader("Range", "bytes=0-"+strconv.Itoa(defaultProbeBytes-1)))
+	} else {
+		o = append(o, WithMethod(http.MethodHead))
+	}
+
+	url, err := resolveURL(mirror, m.probePath)
+	if err != nil {
+		stats.Err = err
+		return stats
+	}
+	start := time.Now()
+	body, err := Download(url, o...)
+	if err != nil {
+		stats.Err = err
+		return stats
+	}
+	stats.Bytes, err = io.Copy(io.Discard, io.LimitReader(body, defaultProbeBytes))
+	body.Close()
+	stats.Elapsed = time.Since(start)
+	stats.Err = err
+	stats.Healthy = err == nil
+	return stats
+}
+
+func (m *MirrorSet) Stats() []MirrorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := make([]MirrorStats, 0, len(m.mirrors))
+	for _, mirror := range m.mirrors {
+		if s, ok := m.stats[mirror]; ok {
+			stats = append(stats, s)
+		} else {
+			stats = append(stats, MirrorStats{URL: mirror, Healthy: true})
+		}
+	}
+	return stats
+}
+
+func (m *MirrorSet) Ranked() []string {
+	stats := m.Stats()
+	slices.SortStableFunc(stats, func(a, b MirrorStats) int {
+		if a.Healthy != b.Healthy {
+			if a.Healthy {
+				return -1
+			}
+			return 1
+		}
+		if a.Probed.IsZero() || b.Probed.IsZero() {
+			return cmp.Compare(btoi(a.Probed.IsZero()), btoi(b.Probed.IsZero()))
+		}
+		return cmp.Compare(a.Elapsed, b.Elapsed)
+	})
+	ranked := make([]string, len(stats))
+	for i, s := range stats {
+		ranked[i] = s.URL
+	}
+	return ranked
+}
+
+func (m *MirrorSet) Download(path string, o ...DownloadOption) (io.ReadCloser, string, error) {
+	ranked := m.Ranked()
+	urls := make([]string, 0, len(ranked))
+	for _, mirror := range ranked {
+		url, err := resolveURL(mirror, path)
+		if err != nil {
+			return nil, "", err
+		}
+		urls = append(urls, url)
+	}
+	body, url, err := DownloadFromMirrors(urls, m.options(append(o, WithMirrorStrategy(InOrder)))...)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, ranked[slices.Index(urls, url)], nil
+}
+
+func (m *MirrorSet) options(o []DownloadOption) []DownloadOption {
+	return append(slices.Clip(m.opts), o...)
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
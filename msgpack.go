@@ -0,0 +1,20 @@
+package dlutil
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func DownloadMsgpack[T any](url string, o ...DownloadOption) (*T, error) {
+	body, err := Download(url, append(o, WithAcceptContentType("application/msgpack, application/x-msgpack, application/vnd.msgpack"))...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	decoder := msgpack.NewDecoder(body)
+	result := new(T)
+	if err := decoder.Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,94 @@
+package dlutil
+
+import (
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type FilePart struct {
+	FieldName   string
+	FileName    string
+	ContentType string
+	Reader      io.Reader
+}
+
+func WithMultipartForm(fields map[string]string, files ...FilePart) DownloadOption {
+	return func(do *DownloadOptions) {
+		boundary := multipart.NewWriter(io.Discard).Boundary()
+		do.Body = &multipartBody{boundary: boundary, fields: fields, files: files}
+		do.BodyContentType = "multipart/form-data; boundary=" + boundary
+		do.PostByDefault = true
+	}
+}
+
+type multipartBody struct {
+	boundary string
+	fields   map[string]string
+	files    []FilePart
+	once     sync.Once
+	pr       *io.PipeReader
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	b.once.Do(b.start)
+	return b.pr.Read(p)
+}
+
+func (b *multipartBody) Close() error {
+	b.once.Do(b.start)
+	return b.pr.Close()
+}
+
+func (b *multipartBody) start() {
+	pr, pw := io.Pipe()
+	b.pr = pr
+	go func() {
+		pw.CloseWithError(b.write(pw))
+	}()
+}
+
+func (b *multipartBody) write(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(b.boundary); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(b.fields))
+	for key := range b.fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if err := mw.WriteField(key, b.fields[key]); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range b.files {
+		contentType := file.ContentType
+		if len(contentType) == 0 {
+			contentType = "application/octet-stream"
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="`+escapeQuotes(file.FieldName)+`"; filename="`+escapeQuotes(file.FileName)+`"`)
+		header.Set("Content-Type", contentType)
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, file.Reader); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
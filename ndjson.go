@@ -0,0 +1,74 @@
+package dlutil
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+)
+
+const ndjsonContentTypes = "application/x-ndjson, application/ndjson, application/jsonl, application/json"
+
+func DownloadNDJSON[T any](url string, o ...DownloadOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		opts := newDownloadOptions(append(o, WithAcceptContentType(ndjsonContentTypes)))
+		body, _, err := doDownload(url, &opts)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer body.Close()
+
+		decoder := newJSONDecoder(body, &opts)
+		for {
+			var item T
+			if err := decoder.Decode(&item); err == io.EOF {
+				return
+			} else if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+func DownloadJSONStream[T any](url string, o ...DownloadOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		opts := newDownloadOptions(append(o, WithAcceptContentType("application/json")))
+		body, _, err := doDownload(url, &opts)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer body.Close()
+
+		decoder := newJSONDecoder(body, &opts)
+		tok, err := decoder.Token()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			yield(zero, errors.New("expected JSON array"))
+			return
+		}
+		for decoder.More() {
+			var item T
+			if err := decoder.Decode(&item); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package dlutil
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+func WithOAuth2(ts oauth2.TokenSource) DownloadOption {
+	return WithTokenSource(func(context.Context) (string, error) {
+		token, err := ts.Token()
+		if err != nil {
+			return "", err
+		}
+		return token.AccessToken, nil
+	})
+}
+
+func WithClientCredentials(clientID, clientSecret, tokenURL string, scopes ...string) DownloadOption {
+	source := &clientCredentialsSource{
+		config: &clientcredentials.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			TokenURL:     tokenURL,
+			Scopes:       scopes,
+		},
+	}
+	return WithTokenSource(source.token)
+}
+
+type clientCredentialsSource struct {
+	mu     sync.Mutex
+	config *clientcredentials.Config
+	ts     oauth2.TokenSource
+}
+
+func (s *clientCredentialsSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	if s.ts == nil || TokenRefreshRequested(ctx) {
+		s.ts = s.config.TokenSource(context.Background())
+	}
+	ts := s.ts
+	s.mu.Unlock()
+
+	token, err := ts.Token()
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,110 @@
+package dlutil
+
+import (
+	"iter"
+	"net/http"
+	"strings"
+)
+
+func DownloadPaged[T any](url string, o ...DownloadOption) iter.Seq2[*T, error] {
+	return paginate(url, o, func(resp *http.Response, _ *T) (string, bool) {
+		if resp == nil {
+			return "", false
+		}
+		next, ok := parseLinkHeader(resp.Header.Values("Link"))["next"]
+		return next, ok
+	})
+}
+
+func DownloadPages[T any](url string, next func(page *T) (nextURL string, ok bool), o ...DownloadOption) iter.Seq2[*T, error] {
+	return paginate(url, o, func(_ *http.Response, page *T) (string, bool) {
+		return next(page)
+	})
+}
+
+func DownloadPagedItems[T any](url string, o ...DownloadOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page, err := range DownloadPaged[[]T](url, o...) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range *page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func paginate[T any](url string, o []DownloadOption, next func(resp *http.Response, page *T) (string, bool)) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		opts := newDownloadOptions(append(o, WithAcceptContentType("application/json")))
+		baseKey := opts.CacheKey
+		for first := true; len(url) > 0; first = false {
+			if len(baseKey) > 0 && !first {
+				opts.CacheKey = baseKey + "#page=" + url
+			}
+			page, resp, err := downloadPage[T](url, opts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page, nil) {
+				return
+			}
+			nextURL, ok := next(resp, page)
+			if !ok {
+				return
+			}
+			if resp != nil && resp.Request != nil {
+				if u, err := resp.Request.URL.Parse(nextURL); err == nil {
+					nextURL = u.String()
+				}
+			}
+			url = nextURL
+		}
+	}
+}
+
+func downloadPage[T any](url string, opts DownloadOptions) (*T, *http.Response, error) {
+	body, resp, err := doDownload(url, &opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer body.Close()
+
+	page := new(T)
+	if err := newJSONDecoder(body, &opts).Decode(page); err != nil {
+		return nil, nil, err
+	}
+	return page, resp, nil
+}
+
+func parseLinkHeader(values []string) map[string]string {
+	links := make(map[string]string)
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
+			for _, param := range strings.Split(params, ";") {
+				key, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+					if _, exists := links[strings.ToLower(rel)]; !exists {
+						links[strings.ToLower(rel)] = target
+					}
+				}
+			}
+		}
+	}
+	return links
+}
@@ -0,0 +1,76 @@
+package dlutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newPagedServer(t *testing.T, pages int, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page < pages {
+			w.Header().Set("Link", fmt.Sprintf(`</items?page=%d>; rel="next", </items?page=1>; rel="first"`, page+1))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "[%d, %d]", page*10, page*10+1)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func collectPagedItems(t *testing.T, url string, o ...DownloadOption) []int {
+	t.Helper()
+	var items []int
+	for item, err := range DownloadPagedItems[int](url, o...) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestDownloadPaged(t *testing.T) {
+	var requests atomic.Int32
+	srv := newPagedServer(t, 3, &requests)
+	items := collectPagedItems(t, srv.URL+"/items")
+	if fmt.Sprint(items) != "[10 11 20 21 30 31]" {
+		t.Fatalf("got %v", items)
+	}
+}
+
+func TestDownloadPagedFromCache(t *testing.T) {
+	for _, key := range []string{"", "items"} {
+		t.Run("key="+key, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := newPagedServer(t, 3, &requests)
+			cache := newTestCache()
+			for range 2 {
+				items := collectPagedItems(t, srv.URL+"/items", WithCache(cache, key, time.Minute))
+				if fmt.Sprint(items) != "[10 11 20 21 30 31]" {
+					t.Fatalf("got %v", items)
+				}
+			}
+			if n := requests.Load(); n != 3 {
+				t.Fatalf("expected 3 origin requests, got %d", n)
+			}
+		})
+	}
+}
+
+func TestParseLinkHeader(t *testing.T) {
+	links := parseLinkHeader([]string{`<https://a/?p=2>; rel="next last", <https://a/?p=1>; rel=prev`, `bogus, <x>`})
+	if links["next"] != "https://a/?p=2" || links["last"] != "https://a/?p=2" || links["prev"] != "https://a/?p=1" || len(links) != 3 {
+		t.Fatalf("got %v", links)
+	}
+}
@@ -0,0 +1,237 @@
+package dlutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrResourceChanged = errors.New("resource changed during parallel download")
+
+func WithParallelChunks(n int, chunkSize int64) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.ParallelChunks = n
+		do.ChunkSize = chunkSize
+	}
+}
+
+func doRequest(url string, opts *DownloadOptions) (*http.Response, error) {
+	if opts.ParallelChunks > 1 && opts.ChunkSize > 0 && opts.Method == http.MethodGet && len(opts.Header.Get("Range")) == 0 {
+		return doParallel(url, opts)
+	}
+	return doWithRetry(url, opts)
+}
+
+func doParallel(url string, opts *DownloadOptions) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(opts.Ctx)
+	resp, err := doWithRetry(url, withRange(opts, ctx, 0, opts.ChunkSize-1, ""))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && resp.Header.Get("Content-Range") == "bytes */0" {
+		drainAndClose(resp.Body)
+		cancel()
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.ContentLength = 0
+		resp.Header.Set("Content-Length", "0")
+		resp.Header.Del("Content-Range")
+		resp.Body = http.NoBody
+		return resp, nil
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		body := resp.Body
+		resp.Body = &readCloser{Reader: body, close: func() error {
+			defer cancel()
+			return body.Close()
+		}}
+		return resp, nil
+	}
+
+	start, end, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok || start != 0 || end != opts.ChunkSize-1 && end != total-1 {
+		resp.Body.Close()
+		cancel()
+		return nil, errors.New("bad content-range: " + resp.Header.Get("Content-Range"))
+	}
+	validator := ifRangeValidator(resp.Header)
+	if len(validator) == 0 && end+1 < total {
+		resp.Body.Close()
+		cancel()
+		return doWithRetry(url, opts)
+	}
+
+	chunkCount := int((total + opts.ChunkSize - 1) / opts.ChunkSize)
+	pr := &parallelReader{
+		first:     resp.Body,
+		cur:       resp.Body,
+		curRemain: end + 1,
+		results:   make([]chan chunkResult, chunkCount),
+		sem:       make(chan struct{}, opts.ParallelChunks),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	for i := range pr.results {
+		pr.results[i] = make(chan chunkResult, 1)
+	}
+
+	pr.wg.Add(1)
+	go func() {
+		defer pr.wg.Done()
+		for i := 1; i < chunkCount; i++ {
+			select {
+			case pr.sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			chunkStart := int64(i) * opts.ChunkSize
+			chunkEnd := min(chunkStart+opts.ChunkSize, total) - 1
+			pr.wg.Add(1)
+			go func(result chan<- chunkResult) {
+				defer pr.wg.Done()
+				chunkOpts := withRange(opts, ctx, chunkStart, chunkEnd, validator)
+				chunkOpts.Result = nil
+				data, err := fetchChunk(url, chunkOpts, chunkEnd-chunkStart+1, validator)
+				result <- chunkResult{data: data, err: err}
+			}(pr.results[i])
+		}
+	}()
+
+	resp.StatusCode = http.StatusOK
+	resp.Status = "200 OK"
+	resp.ContentLength = total
+	resp.Header.Set("Content-Length", strconv.FormatInt(total, 10))
+	resp.Header.Del("Content-Range")
+	resp.Body = pr
+	return resp, nil
+}
+
+func withRange(opts *DownloadOptions, ctx context.Context, start, end int64, validator string) *DownloadOptions {
+	rangeOpts := *opts
+	rangeOpts.Ctx = ctx
+	rangeOpts.Header = opts.Header.Clone()
+	if rangeOpts.Header == nil {
+		rangeOpts.Header = make(http.Header)
+	}
+	rangeOpts.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	if len(validator) > 0 {
+		rangeOpts.Header.Set("If-Range", validator)
+	}
+	return &rangeOpts
+}
+
+func fetchChunk(url string, opts *DownloadOptions, size int64, validator string) ([]byte, error) {
+	resp, err := doWithRetry(url, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent && ifRangeValidator(resp.Header) != validator {
+		return nil, ErrResourceChanged
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, badStatusFromResponse(resp)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, size))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+func parseContentRange(value string) (start, end, total int64, ok bool) {
+	value, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, 0, 0, false
+	}
+	rng, size, found := strings.Cut(value, "/")
+	if !found {
+		return 0, 0, 0, false
+	}
+	first, last, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, 0, false
+	}
+	var err error
+	if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+		return 0, 0, 0, false
+	}
+	if end, err = strconv.ParseInt(last, 10, 64); err != nil {
+		return 0, 0, 0, false
+	}
+	if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+		return 0, 0, 0, false
+	}
+	return start, end, total, start <= end && end < total
+}
+
+type chunkResult struct {
+	data []byte
+	err  error
+}
+
+type parallelReader struct {
+	first     io.ReadCloser
+	cur       io.ReadCloser
+	curRemain int64
+	next      int
+	results   []chan chunkResult
+	sem       chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func (pr *parallelReader) Read(p []byte) (int, error) {
+	for {
+		if pr.cur != nil {
+			n, err := pr.cur.Read(p)
+			pr.curRemain -= int64(n)
+			if err == io.EOF {
+				pr.cur = nil
+				if pr.curRemain > 0 {
+					return n, io.ErrUnexpectedEOF
+				}
+				err = nil
+			}
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		pr.next++
+		if pr.next >= len(pr.results) {
+			return 0, io.EOF
+		}
+		select {
+		case result := <-pr.results[pr.next]:
+			<-pr.sem
+			if result.err != nil {
+				return 0, result.err
+			}
+			pr.cur = io.NopCloser(bytes.NewReader(result.data))
+			pr.curRemain = int64(len(result.data))
+		case <-pr.ctx.Done():
+			return 0, pr.ctx.Err()
+		}
+	}
+}
+
+func (pr *parallelReader) Close() error {
+	pr.cancel()
+	err := pr.first.Close()
+	pr.wg.Wait()
+	return err
+}
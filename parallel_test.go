@@ -0,0 +1,84 @@
+package dlutil
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newRangeServer(t *testing.T, content func(r *http.Request) ([]byte, string)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, etag := content(r)
+		if len(etag) > 0 {
+			w.Header().Set("ETag", etag)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestParallelChunks(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1000)
+	srv := newRangeServer(t, func(*http.Request) ([]byte, string) { return data, `"v1"` })
+
+	for _, chunkSize := range []int64{1000, 4096, int64(len(data)), 1 << 20} {
+		got, err := DownloadBytes(srv.URL, WithParallelChunks(4, chunkSize))
+		if err != nil {
+			t.Fatalf("chunk size %d: %v", chunkSize, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("chunk size %d: got %d bytes, want %d", chunkSize, len(got), len(data))
+		}
+	}
+}
+
+func TestParallelChunksEmpty(t *testing.T) {
+	srv := newRangeServer(t, func(*http.Request) ([]byte, string) { return nil, `"v1"` })
+	got, err := DownloadBytes(srv.URL, WithParallelChunks(4, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("got %d bytes", len(got))
+	}
+}
+
+func TestParallelChunksChanged(t *testing.T) {
+	v1 := bytes.Repeat([]byte("a"), 10000)
+	v2 := bytes.Repeat([]byte("b"), 10000)
+	var requests atomic.Int32
+	srv := newRangeServer(t, func(*http.Request) ([]byte, string) {
+		if requests.Add(1) == 1 {
+			return v1, `"v1"`
+		}
+		return v2, `"v2"`
+	})
+	if _, err := DownloadBytes(srv.URL, WithParallelChunks(2, 1000)); !errors.Is(err, ErrResourceChanged) {
+		t.Fatalf("expected ErrResourceChanged, got %v", err)
+	}
+}
+
+func TestParallelChunksNoValidator(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 10000)
+	var requests atomic.Int32
+	srv := newRangeServer(t, func(*http.Request) ([]byte, string) {
+		requests.Add(1)
+		return data, ""
+	})
+	got, err := DownloadBytes(srv.URL, WithParallelChunks(4, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %d bytes", len(got))
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("expected probe plus single-stream request, got %d requests", n)
+	}
+}
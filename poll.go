@@ -0,0 +1,87 @@
+package dlutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+var ErrInvalidInterval = errors.New("interval must be positive")
+
+type PollHandler func(content []byte, err error) error
+
+func Poll(url string, interval time.Duration, handler PollHandler, o ...DownloadOption) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+	opts := newDownloadOptions(o)
+	opts.Cache = nil
+
+	p := &poller{url: url, opts: opts}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		content, changed, err := p.poll()
+		if err != nil || changed {
+			if err := handler(content, err); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-opts.Ctx.Done():
+			return opts.Ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+type poller struct {
+	url          string
+	opts         DownloadOptions
+	etag         string
+	lastModified string
+	digest       []byte
+	content      []byte
+	resp         *http.Response
+}
+
+func (p *poller) poll() ([]byte, bool, error) {
+	opts := p.opts
+	opts.Header = p.opts.Header.Clone()
+	if opts.Header == nil {
+		opts.Header = make(http.Header)
+	}
+	if len(p.etag) > 0 {
+		opts.Header.Set("If-None-Match", p.etag)
+	}
+	if len(p.lastModified) > 0 {
+		opts.Header.Set("If-Modified-Since", p.lastModified)
+	}
+
+	body, resp, err := doDownload(p.url, &opts)
+	if err != nil {
+		return nil, false, err
+	}
+	defer body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, false, nil
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, false, err
+	}
+	p.etag = resp.Header.Get("ETag")
+	p.lastModified = resp.Header.Get("Last-Modified")
+	p.content, p.resp = content, resp
+
+	digest := sha256.Sum256(content)
+	if p.digest != nil && bytes.Equal(p.digest, digest[:]) {
+		return nil, false, nil
+	}
+	p.digest = digest[:]
+	return content, true, nil
+}
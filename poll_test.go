@@ -0,0 +1,57 @@
+package dlutil
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	var requests, conditional atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		version := fmt.Sprintf(`"v%d"`, (n+1)/2)
+		if r.Header.Get("If-None-Match") == version {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", version)
+		w.Write([]byte(version))
+	}))
+	defer srv.Close()
+
+	stop := errors.New("stop")
+	var got []string
+	err := Poll(srv.URL, time.Millisecond, func(content []byte, err error) error {
+		if err != nil {
+			return err
+		}
+		if got = append(got, string(content)); len(got) == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected stop, got %v", err)
+	}
+	if fmt.Sprint(got) != `["v1" "v2" "v3"]` {
+		t.Fatalf("got %v", got)
+	}
+	if conditional.Load() != 2 {
+		t.Fatalf("expected 2 not-modified responses, got %d", conditional.Load())
+	}
+}
+
+func TestPollInvalidInterval(t *testing.T) {
+	handler := func([]byte, error) error { return nil }
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := Poll("http://example.com", interval, handler); !errors.Is(err, ErrInvalidInterval) {
+			t.Fatalf("interval %v: expected ErrInvalidInterval, got %v", interval, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package dlutil
+
+import (
+	"errors"
+)
+
+const defaultPrefetchConcurrency = 4
+
+var ErrNoCache = errors.New("no cache configured")
+
+func WithConcurrency(n int) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.Concurrency = n
+	}
+}
+
+func Prefetch(urls []string, o ...DownloadOption) error {
+	opts := newDownloadOptions(o)
+	if opts.Cache == nil {
+		return ErrNoCache
+	}
+
+	var stale []string
+	var indices []int
+	var errs []error
+	for i, url := range urls {
+		key, err := requestURL(url, &opts)
+		if err != nil {
+			errs = append(errs, &BatchError{Index: i, URL: url, Err: err})
+			continue
+		}
+		if cacheFresh(key, &opts) {
+			continue
+		}
+		stale = append(stale, url)
+		indices = append(indices, i)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultPrefetchConcurrency
+	}
+	o = append(o, WithCache(opts.Cache, "", opts.CacheTTL), WithBatchHandler(nil))
+	if _, err := DownloadAll(stale, concurrency, o...); err != nil {
+		for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+			var batchErr *BatchError
+			if errors.As(err, &batchErr) {
+				batchErr.Index = indices[batchErr.Index]
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,28 @@
+package dlutil
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const protoContentTypes = "application/x-protobuf, application/protobuf, application/vnd.google.protobuf"
+
+func DownloadProto[T proto.Message](url string, o ...DownloadOption) (T, error) {
+	var result T
+	body, err := Download(url, append(o, WithHeader("Accept", protoContentTypes), WithAcceptContentType(protoContentTypes))...)
+	if err != nil {
+		return result, err
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return result, err
+	}
+	result = result.ProtoReflect().New().Interface().(T)
+	if err := proto.Unmarshal(content, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
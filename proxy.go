@@ -0,0 +1,171 @@
+package dlutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+func WithProxy(proxyURL string) DownloadOption {
+	return withTransportOption("proxy "+proxyURL, func(t *http.Transport) error {
+		u, err := neturl.Parse(proxyURL)
+		if err != nil {
+			return err
+		}
+		if len(u.Host) == 0 {
+			return errors.New("invalid proxy url: " + proxyURL)
+		}
+		t.Proxy = http.ProxyURL(u)
+		return nil
+	})
+}
+
+func WithSOCKS5(addr string, auth *neturl.Userinfo) DownloadOption {
+	return WithOptions(func(do *DownloadOptions) {
+		do.SOCKS5 = true
+	}, withTransportOption("socks5 "+addr+" "+auth.String(), func(t *http.Transport) error {
+		var proxyAuth *proxy.Auth
+		if auth != nil {
+			password, _ := auth.Password()
+			proxyAuth = &proxy.Auth{User: auth.Username(), Password: password}
+		}
+		dialer, err := proxy.SOCKS5("tcp", addr, proxyAuth, proxy.Direct)
+		if err != nil {
+			return err
+		}
+		t.Proxy = nil
+		t.DialContext = dialer.(proxy.ContextDialer).DialContext
+		return nil
+	}))
+}
+
+type ProxyStrategy int
+
+const (
+	RoundRobin ProxyStrategy = iota
+	RandomProxy
+)
+
+var ErrNoProxies = errors.New("proxy pool is empty")
+
+type ProxyPool struct {
+	mu            sync.Mutex
+	strategy      ProxyStrategy
+	proxies       []*pooledProxy
+	next          int
+	maxFailures   int
+	ejectDuration time.Duration
+}
+
+type pooledProxy struct {
+	url          *neturl.URL
+	failures     int
+	ejectedUntil time.Time
+}
+
+type proxyKey struct{}
+
+func NewProxyPool(strategy ProxyStrategy, proxyURLs ...string) (*ProxyPool, error) {
+	if len(proxyURLs) == 0 {
+		return nil, ErrNoProxies
+	}
+	pool := &ProxyPool{
+		strategy:      strategy,
+		maxFailures:   3,
+		ejectDuration: time.Minute,
+	}
+	for _, proxyURL := range proxyURLs {
+		u, err := neturl.Parse(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		if len(u.Host) == 0 {
+			return nil, errors.New("invalid proxy url: " + proxyURL)
+		}
+		pool.proxies = append(pool.proxies, &pooledProxy{url: u})
+	}
+	return pool, nil
+}
+
+func (p *ProxyPool) SetEjection(maxFailures int, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxFailures = maxFailures
+	p.ejectDuration = duration
+}
+
+func (p *ProxyPool) pick() *pooledProxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	available := make([]*pooledProxy, 0, len(p.proxies))
+	var soonest *pooledProxy
+	for i := range p.proxies {
+		proxy := p.proxies[(p.next+i)%len(p.proxies)]
+		if !now.Before(proxy.ejectedUntil) {
+			available = append(available, proxy)
+		} else if soonest == nil || proxy.ejectedUntil.Before(soonest.ejectedUntil) {
+			soonest = proxy
+		}
+	}
+	if len(available) == 0 {
+		return soonest
+	}
+	if p.strategy == RandomProxy {
+		return available[rand.IntN(len(available))]
+	}
+	p.next = (slices.Index(p.proxies, available[0]) + 1) % len(p.proxies)
+	return available[0]
+}
+
+func (p *ProxyPool) report(proxy *pooledProxy, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ok {
+		proxy.failures = 0
+		return
+	}
+	proxy.failures++
+	if p.maxFailures > 0 && proxy.failures >= p.maxFailures {
+		proxy.failures = 0
+		proxy.ejectedUntil = time.Now().Add(p.ejectDuration)
+	}
+}
+
+func (p *ProxyPool) proxyForRequest(req *http.Request) (*neturl.URL, error) {
+	if proxy, ok := req.Context().Value(proxyKey{}).(*pooledProxy); ok {
+		return proxy.url, nil
+	}
+	return p.pick().url, nil
+}
+
+func (p *ProxyPool) middleware(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		proxy := p.pick()
+		req = req.WithContext(context.WithValue(req.Context(), proxyKey{}, proxy))
+		resp, err := next(req)
+		if req.Context().Err() == nil {
+			p.report(proxy, err == nil && resp.StatusCode != http.StatusProxyAuthRequired)
+		}
+		return resp, err
+	}
+}
+
+func WithProxyPool(pool *ProxyPool) DownloadOption {
+	return WithOptions(
+		withTransportOption(fmt.Sprintf("proxy-pool %p", pool), func(t *http.Transport) error {
+			t.Proxy = pool.proxyForRequest
+			return nil
+		}),
+		WithMiddleware(pool.middleware),
+	)
+}
@@ -0,0 +1,21 @@
+package dlutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSOCKS5RejectsLocalResolution(t *testing.T) {
+	options := map[string]DownloadOption{
+		"resolve":   WithResolve("example.com", "127.0.0.1"),
+		"dns cache": WithDNSCache(0),
+	}
+	for name, option := range options {
+		t.Run(name, func(t *testing.T) {
+			_, err := DownloadBytes("http://example.com/", WithSOCKS5("127.0.0.1:1", nil), option)
+			if !errors.Is(err, ErrResolveWithSOCKS5) {
+				t.Fatalf("expected ErrResolveWithSOCKS5, got %v", err)
+			}
+		})
+	}
+}
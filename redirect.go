@@ -0,0 +1,111 @@
+package dlutil
+
+import (
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+)
+
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+func WithMaxRedirects(n int) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.MaxRedirects = n
+		do.NoRedirects = false
+	}
+}
+
+func WithNoRedirects() DownloadOption {
+	return func(do *DownloadOptions) {
+		do.NoRedirects = true
+	}
+}
+
+func WithRedirectHook(hook func(prev, next *neturl.URL) error) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.RedirectHooks = append(do.RedirectHooks, hook)
+	}
+}
+
+func WithRedirectCredentials(keep bool) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.RedirectCredentials = keep
+	}
+}
+
+func applyRedirectPolicy(opts *DownloadOptions) {
+	client := *opts.Client
+	check := client.CheckRedirect
+	result := opts.Result
+	var mu sync.Mutex
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if opts.NoRedirects {
+			return http.ErrUseLastResponse
+		}
+		if opts.MaxRedirects > 0 {
+			if len(via) > opts.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+		} else if check != nil {
+			if err := check(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= 10 {
+			return ErrTooManyRedirects
+		}
+		host := req.URL.Host
+		for _, hook := range opts.RedirectHooks {
+			if err := hook(via[len(via)-1].URL, req.URL); err != nil {
+				return err
+			}
+		}
+		if req.URL.Host != host {
+			req.Host = ""
+		}
+		if err := checkHost(req.URL, opts); err != nil {
+			return err
+		}
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			if opts.RedirectCredentials {
+				copyCredentials(req, via[0], opts)
+			} else {
+				stripCredentials(req)
+			}
+		}
+		if result != nil {
+			chain := make([]string, 0, len(via))
+			for _, r := range via[1:] {
+				chain = append(chain, r.URL.String())
+			}
+			mu.Lock()
+			result.Redirects = append(chain, req.URL.String())
+			mu.Unlock()
+		}
+		return nil
+	}
+	opts.Client = &client
+}
+
+var credentialHeaders = []string{"Authorization", "Cookie", "Cookie2", "WWW-Authenticate"}
+
+func stripCredentials(req *http.Request) {
+	for _, key := range credentialHeaders {
+		req.Header.Del(key)
+	}
+}
+
+func copyCredentials(req, orig *http.Request, opts *DownloadOptions) {
+	if auth := orig.Header.Get("Authorization"); len(auth) > 0 {
+		req.Header.Set("Authorization", auth)
+	}
+	if len(req.Header.Get("Cookie")) == 0 {
+		for _, cookie := range opts.Header.Values("Cookie") {
+			req.Header.Add("Cookie", cookie)
+		}
+		for _, cookie := range opts.Cookies {
+			req.AddCookie(cookie)
+		}
+	}
+}
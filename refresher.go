@@ -0,0 +1,178 @@
+package dlutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/razzie/razcache"
+)
+
+type RefreshHandler func(url string, content []byte, err error)
+
+type Refresher struct {
+	cache    razcache.Cache
+	ttl      time.Duration
+	schedule Schedule
+	opts     []DownloadOption
+	mu       sync.Mutex
+	targets  map[string]*refreshTarget
+	subs     map[int]RefreshHandler
+	nextSub  int
+	wake     chan struct{}
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+type refreshTarget struct {
+	mu      sync.Mutex
+	url     string
+	key     string
+	poller  *poller
+	fetched bool
+}
+
+func NewRefresher(cache razcache.Cache, ttl time.Duration, schedule Schedule, o ...DownloadOption) *Refresher {
+	if schedule == nil {
+		schedule = Manual
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Refresher{
+		cache:    cache,
+		ttl:      ttl,
+		schedule: schedule,
+		opts:     o,
+		targets:  make(map[string]*refreshTarget),
+		subs:     make(map[int]RefreshHandler),
+		wake:     make(chan struct{}, 1),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go r.run(ctx)
+	return r
+}
+
+func (r *Refresher) Add(url, key string) {
+	r.mu.Lock()
+	if _, ok := r.targets[url]; !ok {
+		r.targets[url] = &refreshTarget{url: url, key: key}
+	}
+	r.mu.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Refresher) Remove(url string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.targets, url)
+}
+
+func (r *Refresher) Subscribe(handler RefreshHandler) (unsubscribe func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := r.nextSub
+	r.nextSub++
+	r.subs[id] = handler
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.subs, id)
+	}
+}
+
+func (r *Refresher) Refresh(ctx context.Context) {
+	r.refresh(ctx, false)
+}
+
+func (r *Refresher) Close() {
+	r.cancel()
+	<-r.done
+}
+
+func (r *Refresher) run(ctx context.Context) {
+	defer close(r.done)
+	next := r.schedule(time.Now())
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+	if next.IsZero() {
+		timer.Stop()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+			r.refresh(ctx, true)
+		case <-timer.C:
+			r.refresh(ctx, false)
+			if next = r.schedule(time.Now()); !next.IsZero() {
+				timer.Reset(time.Until(next))
+			}
+		}
+	}
+}
+
+func (r *Refresher) refresh(ctx context.Context, pendingOnly bool) {
+	r.mu.Lock()
+	targets := make([]*refreshTarget, 0, len(r.targets))
+	for _, t := range r.targets {
+		targets = append(targets, t)
+	}
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.refreshTarget(ctx, t, pendingOnly)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Refresher) refreshTarget(ctx context.Context, t *refreshTarget, pendingOnly bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if pendingOnly && t.fetched {
+		return
+	}
+	if t.poller == nil {
+		opts := newDownloadOptions(r.opts)
+		opts.Cache = nil
+		t.poller = &poller{url: t.url, opts: opts}
+	}
+	t.poller.opts.Ctx = ctx
+	content, changed, err := t.poller.poll()
+	if ctx.Err() != nil {
+		return
+	}
+	t.fetched = true
+	if err == nil && t.poller.resp != nil {
+		opts := t.poller.opts
+		opts.Cache = r.cache
+		opts.CacheKey = t.key
+		opts.CacheTTL = r.ttl
+		resp := t.poller.resp
+		err = writeCache(t.url, &opts, resp, newCacheEntry(&opts, resp.Header, t.poller.content))
+	}
+	if err != nil || changed {
+		r.notify(t.url, content, err)
+	}
+}
+
+func (r *Refresher) notify(url string, content []byte, err error) {
+	r.mu.Lock()
+	subs := make([]RefreshHandler, 0, len(r.subs))
+	for _, handler := range r.subs {
+		subs = append(subs, handler)
+	}
+	r.mu.Unlock()
+	for _, handler := range subs {
+		handler(url, content, err)
+	}
+}
@@ -0,0 +1,66 @@
+package dlutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefresherWritesCacheEntries(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	cache := newTestCache()
+	r := NewRefresher(cache, time.Minute, Manual)
+	defer r.Close()
+	notified := make(chan string, 1)
+	r.Subscribe(func(url string, content []byte, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		notified <- string(content)
+	})
+	r.Add(srv.URL, "greeting")
+	select {
+	case content := <-notified:
+		if content != "hello" {
+			t.Fatalf("got %q", content)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no refresh")
+	}
+
+	e, err := getCacheEntry(srv.URL, "greeting", &DownloadOptions{Cache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.body != "hello" || e.ETag != `"v1"` || e.Header.Get("Content-Type") != "text/plain" || !e.fresh() {
+		t.Fatalf("got %+v", e)
+	}
+
+	r.Refresh(context.Background())
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+	var result Result
+	content, err := DownloadString(srv.URL, WithCache(cache, "greeting", time.Minute), WithResult(&result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "hello" || !result.CacheHit || requests.Load() != 2 {
+		t.Fatalf("got %q, cache hit %v, %d requests", content, result.CacheHit, requests.Load())
+	}
+}
@@ -0,0 +1,45 @@
+package dlutil
+
+import (
+	"io"
+	"net/http"
+)
+
+type Response struct {
+	Body       io.ReadCloser
+	StatusCode int
+	Header     http.Header
+	URL        string
+}
+
+func DownloadWithResponse(url string, o ...DownloadOption) (*Response, error) {
+	opts := newDownloadOptions(o)
+	body, resp, err := doDownload(url, &opts)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := resolveURL(opts.BaseURL, url); err == nil {
+		url = resolved
+	}
+	return newResponse(url, body, resp), nil
+}
+
+func newResponse(url string, body io.ReadCloser, resp *http.Response) *Response {
+	if resp == nil {
+		return &Response{
+			Body:       body,
+			StatusCode: http.StatusOK,
+			Header:     make(http.Header),
+			URL:        url,
+		}
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		url = resp.Request.URL.String()
+	}
+	return &Response{
+		Body:       body,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		URL:        url,
+	}
+}
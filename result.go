@@ -0,0 +1,50 @@
+package dlutil
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+type Result struct {
+	URL        string
+	StatusCode int
+	Bytes      int64
+	Duration   time.Duration
+	CacheHit   bool
+	Retries    int
+	Redirects  []string
+}
+
+func WithResult(result *Result) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.Result = result
+	}
+}
+
+type resultReader struct {
+	r      io.ReadCloser
+	result *Result
+	start  time.Time
+	once   sync.Once
+}
+
+func (r *resultReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.result.Bytes += int64(n)
+	if err != nil {
+		r.finish()
+	}
+	return n, err
+}
+
+func (r *resultReader) Close() error {
+	r.finish()
+	return r.r.Close()
+}
+
+func (r *resultReader) finish() {
+	r.once.Do(func() {
+		r.result.Duration = time.Since(r.start)
+	})
+}
@@ -0,0 +1,233 @@
+package dlutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type BackoffStrategy func(attempt int) time.Duration
+
+var DefaultBackoff = ExponentialBackoff(200*time.Millisecond, 10*time.Second, 0.2)
+
+func ExponentialBackoff(initial, max time.Duration, jitter float64) BackoffStrategy {
+	return func(attempt int) time.Duration {
+		delay := initial
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		if jitter > 0 {
+			delta := float64(delay) * jitter
+			delay += time.Duration(delta * (2*rand.Float64() - 1))
+		}
+		if delay < 0 {
+			delay = 0
+		}
+		return delay
+	}
+}
+
+func ConstantBackoff(delay time.Duration) BackoffStrategy {
+	return func(int) time.Duration {
+		return delay
+	}
+}
+
+type RetryPolicy func(attempt int, resp *http.Response, err error) (retry bool, delay time.Duration)
+
+func DefaultRetryPolicy(attempts int, backoff BackoffStrategy) RetryPolicy {
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	return func(attempt int, resp *http.Response, err error) (bool, time.Duration) {
+		if attempt >= attempts {
+			return false, 0
+		}
+		if err != nil {
+			return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded), backoff(attempt)
+		}
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+			if retryAfter, ok := parseRetryAfter(resp.Header); ok {
+				return true, retryAfter
+			}
+			return true, backoff(attempt)
+		case resp.StatusCode >= 500:
+			return true, backoff(attempt)
+		default:
+			return false, 0
+		}
+	}
+}
+
+func WithRetry(attempts int, backoff BackoffStrategy) DownloadOption {
+	return WithRetryPolicy(DefaultRetryPolicy(attempts, backoff))
+}
+
+func WithRetryPolicy(policy RetryPolicy) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.RetryPolicy = policy
+	}
+}
+
+func WithRetryNonIdempotent() DownloadOption {
+	return func(do *DownloadOptions) {
+		do.RetryNonIdempotent = true
+	}
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return len(req.Header.Get("Idempotency-Key")) > 0 || len(req.Header.Get("X-Idempotency-Key")) > 0
+}
+
+func doWithRetry(url string, opts *DownloadOptions) (*http.Response, error) {
+	if opts.RetryPolicy == nil && opts.TokenSource == nil && opts.DigestAuth == nil {
+		req, err := newRequest(url, opts, opts.Body, false)
+		if err != nil {
+			return nil, err
+		}
+		return sendAttempt(req, opts)
+	}
+
+	body, rewind, err := rewindableBody(opts.Body)
+	if err != nil {
+		return nil, err
+	}
+	var contentLength int64
+	refreshToken := false
+	digestRetried := false
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && body != nil {
+			if body, err = rewind(); err != nil {
+				return nil, err
+			}
+		}
+		req, err := newRequest(url, opts, body, refreshToken)
+		if err != nil {
+			return nil, err
+		}
+		if attempt == 1 && req.GetBody != nil {
+			contentLength = req.ContentLength
+			rewind = func() (io.Reader, error) { return req.GetBody() }
+		} else if attempt > 1 && req.ContentLength == 0 && contentLength > 0 {
+			req.ContentLength = contentLength
+		}
+		resp, err := sendAttempt(req, opts)
+		if opts.Result != nil {
+			opts.Result.Retries = attempt - 1
+		}
+		if opts.Ctx.Err() != nil {
+			return resp, err
+		}
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && opts.TokenSource != nil && !refreshToken {
+			drainAndClose(resp.Body)
+			refreshToken = true
+			continue
+		}
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && opts.DigestAuth != nil && !digestRetried && opts.DigestAuth.setChallenge(resp.Header) {
+			drainAndClose(resp.Body)
+			digestRetried = true
+			continue
+		}
+		if opts.RetryPolicy == nil || !opts.RetryNonIdempotent && !isIdempotent(req) {
+			return resp, err
+		}
+		retry, delay := opts.RetryPolicy(attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			drainAndClose(resp.Body)
+		}
+		if err := sleepContext(opts.Ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func rewindableBody(body io.Reader) (io.Reader, func() (io.Reader, error), error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, nil, nil
+	case *bytes.Buffer:
+		return b, nil, nil
+	case io.Seeker:
+		offset, err := b.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, nil, err
+		}
+		return body, func() (io.Reader, error) {
+			_, err := b.Seek(offset, io.SeekStart)
+			return body, err
+		}, nil
+	default:
+		r := &replayReader{r: body}
+		return r, r.replay, nil
+	}
+}
+
+type replayReader struct {
+	r   io.Reader
+	buf bytes.Buffer
+	eof bool
+}
+
+func (r *replayReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.buf.Write(p[:n])
+	r.eof = errors.Is(err, io.EOF)
+	return n, err
+}
+
+func (r *replayReader) replay() (io.Reader, error) {
+	if r.eof {
+		return bytes.NewReader(r.buf.Bytes()), nil
+	}
+	return io.MultiReader(bytes.NewReader(r.buf.Bytes()), r), nil
+}
+
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if len(value) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+func drainAndClose(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, 64<<10)
+	body.Close()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package dlutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type onlyReader struct {
+	io.Reader
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if attempts.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	const payload = "request payload"
+	bodies := map[string]func() io.Reader{
+		"bytes buffer": func() io.Reader { return bytes.NewBufferString(payload) },
+		"seeker":       func() io.Reader { return io.NewSectionReader(strings.NewReader(payload), 0, int64(len(payload))) },
+		"stream":       func() io.Reader { return onlyReader{strings.NewReader(payload)} },
+	}
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			got, err := DownloadString(srv.URL, WithMethod(http.MethodPut), WithBody(body(), "text/plain"), WithRetry(2, ConstantBackoff(0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != payload {
+				t.Fatalf("got %q, want %q", got, payload)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second, 0)
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 5: time.Second, 50: time.Second} {
+		if got := backoff(attempt); got != want {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, want)
+		}
+	}
+	jittered := ExponentialBackoff(time.Second, time.Second, 0.2)
+	for range 100 {
+		if got := jittered(1); got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("jittered delay %v out of range", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for value, want := range map[string]time.Duration{"3": 3 * time.Second, " 0 ": 0, "Thu, 01 Jan 1970 00:00:00 GMT": 0} {
+		got, ok := parseRetryAfter(http.Header{"Retry-After": {value}})
+		if !ok || got != want {
+			t.Errorf("%q: got %v, %v", value, got, ok)
+		}
+	}
+	for _, value := range []string{"", "-1", "soon"} {
+		if _, ok := parseRetryAfter(http.Header{"Retry-After": {value}}); ok {
+			t.Errorf("%q: expected no delay", value)
+		}
+	}
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(http.Header{"Retry-After": {future}}); !ok || got < 59*time.Minute || got > time.Hour {
+		t.Errorf("%q: got %v, %v", future, got, ok)
+	}
+}
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	policy := DefaultRetryPolicy(3, ConstantBackoff(time.Second))
+	status := func(code int, header http.Header) *http.Response {
+		return &http.Response{StatusCode: code, Header: header}
+	}
+	cases := []struct {
+		attempt int
+		resp    *http.Response
+		err     error
+		retry   bool
+		delay   time.Duration
+	}{
+		{1, status(http.StatusInternalServerError, nil), nil, true, time.Second},
+		{2, status(http.StatusServiceUnavailable, http.Header{"Retry-After": {"7"}}), nil, true, 7 * time.Second},
+		{1, status(http.StatusTooManyRequests, nil), nil, true, time.Second},
+		{1, status(http.StatusNotFound, nil), nil, false, 0},
+		{1, nil, io.ErrUnexpectedEOF, true, time.Second},
+		{1, nil, context.Canceled, false, time.Second},
+		{3, status(http.StatusInternalServerError, nil), nil, false, 0},
+	}
+	for i, c := range cases {
+		retry, delay := policy(c.attempt, c.resp, c.err)
+		if retry != c.retry || retry && delay != c.delay {
+			t.Errorf("case %d: got %v, %v", i, retry, delay)
+		}
+	}
+}
+
+func TestRetryAttempts(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	var result Result
+	_, err := DownloadString(srv.URL, WithRetry(3, ConstantBackoff(0)), WithResult(&result))
+	var statusErr *BadStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %v", err)
+	}
+	if attempts.Load() != 3 || result.Retries != 2 {
+		t.Fatalf("got %d attempts, %d retries", attempts.Load(), result.Retries)
+	}
+}
+
+func TestRetryIdempotency(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	cases := map[string]struct {
+		o    []DownloadOption
+		want int32
+	}{
+		"post":             {nil, 1},
+		"post with key":    {[]DownloadOption{WithHeader("Idempotency-Key", "abc")}, 2},
+		"post with opt-in": {[]DownloadOption{WithRetryNonIdempotent()}, 2},
+		"patch":            {[]DownloadOption{WithMethod(http.MethodPatch)}, 1},
+		"delete":           {[]DownloadOption{WithMethod(http.MethodDelete)}, 2},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			attempts.Store(0)
+			o := append([]DownloadOption{WithMethod(http.MethodPost), WithRetry(3, ConstantBackoff(0))}, c.o...)
+			DownloadString(srv.URL, o...)
+			if n := attempts.Load(); n != c.want {
+				t.Fatalf("got %d attempts, want %d", n, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package dlutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	neturl "net/url"
+	"sync"
+	"time"
+
+	"github.com/razzie/razcache"
+)
+
+type Session struct {
+	*Downloader
+	cache   razcache.Cache
+	key     string
+	ttl     time.Duration
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	cookies map[string]sessionCookie
+	token   string
+}
+
+type sessionCookie struct {
+	URL       string `json:"url"`
+	SetCookie string `json:"set_cookie"`
+}
+
+type sessionState struct {
+	Cookies []sessionCookie `json:"cookies,omitempty"`
+	Token   string          `json:"token,omitempty"`
+}
+
+func (d *Downloader) NewSession(cache razcache.Cache, id string, ttl time.Duration) (*Session, error) {
+	s := &Session{
+		cache: cache,
+		key:   "session:" + id,
+		ttl:   ttl,
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	s.Downloader = NewDownloader(d.options([]DownloadOption{WithCookieJar(s)})...)
+	return s, nil
+}
+
+func (s *Session) Reload() error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	var state sessionState
+	if content, err := s.cache.Get(s.key); err == nil {
+		if err := json.Unmarshal([]byte(content), &state); err != nil {
+			return err
+		}
+	}
+
+	cookies := make(map[string]sessionCookie, len(state.Cookies))
+	now := time.Now()
+	for _, c := range state.Cookies {
+		u, err := neturl.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		cookie, err := http.ParseSetCookie(c.SetCookie)
+		if err != nil || (!cookie.Expires.IsZero() && cookie.Expires.Before(now)) {
+			continue
+		}
+		jar.SetCookies(u, []*http.Cookie{cookie})
+		cookies[sessionCookieKey(u, cookie)] = c
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jar = jar
+	s.cookies = cookies
+	s.token = state.Token
+	return nil
+}
+
+func (s *Session) SetCookies(u *neturl.URL, cookies []*http.Cookie) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jar.SetCookies(u, cookies)
+	origin := (&neturl.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+	for _, cookie := range cookies {
+		key := sessionCookieKey(u, cookie)
+		if cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())) {
+			delete(s.cookies, key)
+			continue
+		}
+		persisted := *cookie
+		if persisted.MaxAge > 0 {
+			persisted.Expires = time.Now().Add(time.Duration(persisted.MaxAge) * time.Second)
+			persisted.MaxAge = 0
+		}
+		s.cookies[key] = sessionCookie{URL: origin, SetCookie: persisted.String()}
+	}
+	s.save()
+}
+
+func (s *Session) Cookies(u *neturl.URL) []*http.Cookie {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jar.Cookies(u)
+}
+
+func (s *Session) SetToken(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+	return s.save()
+}
+
+func (s *Session) Token() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+func (s *Session) TokenSource(source TokenSource) TokenSource {
+	return func(ctx context.Context) (string, error) {
+		if token := s.Token(); len(token) > 0 && !TokenRefreshRequested(ctx) {
+			return token, nil
+		}
+		token, err := source(ctx)
+		if err != nil {
+			return "", err
+		}
+		return token, s.SetToken(token)
+	}
+}
+
+func (s *Session) Clear() error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jar = jar
+	s.cookies = make(map[string]sessionCookie)
+	s.token = ""
+	return s.cache.Del(s.key)
+}
+
+func (s *Session) save() error {
+	state := sessionState{Token: s.token}
+	for _, c := range s.cookies {
+		state.Cookies = append(state.Cookies, c)
+	}
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.cache.Set(s.key, string(content), s.ttl)
+}
+
+func sessionCookieKey(u *neturl.URL, cookie *http.Cookie) string {
+	domain := cookie.Domain
+	if len(domain) == 0 {
+		domain = u.Hostname()
+	}
+	return domain + ";" + cookie.Path + ";" + cookie.Name
+}
@@ -0,0 +1,68 @@
+package dlutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"aead.dev/minisign"
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+var ErrBadSignature = errors.New("signature verification failed")
+
+type SignatureVerifier func(content, signature []byte) error
+
+func NewOpenPGPVerifier(armoredKeyRing []byte) (SignatureVerifier, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armoredKeyRing))
+	if err != nil {
+		return nil, err
+	}
+	return func(content, signature []byte) error {
+		check := openpgp.CheckDetachedSignature
+		if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN")) {
+			check = openpgp.CheckArmoredDetachedSignature
+		}
+		if _, err := check(keyring, bytes.NewReader(content), bytes.NewReader(signature), nil); err != nil {
+			return errors.Join(ErrBadSignature, err)
+		}
+		return nil
+	}, nil
+}
+
+func NewMinisignVerifier(publicKey string) (SignatureVerifier, error) {
+	var key minisign.PublicKey
+	if err := key.UnmarshalText([]byte(publicKey)); err != nil {
+		return nil, err
+	}
+	return func(content, signature []byte) error {
+		if !minisign.Verify(key, content, signature) {
+			return ErrBadSignature
+		}
+		return nil
+	}, nil
+}
+
+func WithSignature(verifier SignatureVerifier, sigURL string) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.SignatureVerifier = verifier
+		do.SignatureURL = sigURL
+	}
+}
+
+func verifySignature(body io.ReadCloser, opts *DownloadOptions) (io.ReadCloser, error) {
+	defer body.Close()
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := DownloadBytes(opts.SignatureURL, inheritOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.SignatureVerifier(content, signature); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
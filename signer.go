@@ -0,0 +1,62 @@
+package dlutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+)
+
+type RequestSigner func(req *http.Request) error
+
+type HMACSignerConfig struct {
+	Header       string
+	Prefix       string
+	Hash         func() hash.Hash
+	Canonicalize func(req *http.Request, body []byte) (string, error)
+	Encode       func(sum []byte) string
+}
+
+func WithRequestSigner(signer RequestSigner) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.RequestSigners = append(do.RequestSigners, signer)
+	}
+}
+
+func WithHMACSignature(key []byte, config HMACSignerConfig) DownloadOption {
+	return WithRequestSigner(NewHMACSigner(key, config))
+}
+
+func NewHMACSigner(key []byte, config HMACSignerConfig) RequestSigner {
+	if len(config.Header) == 0 {
+		config.Header = "X-Signature"
+	}
+	if config.Hash == nil {
+		config.Hash = sha256.New
+	}
+	if config.Canonicalize == nil {
+		config.Canonicalize = CanonicalHMACRequest
+	}
+	if config.Encode == nil {
+		config.Encode = hex.EncodeToString
+	}
+	return func(req *http.Request) error {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return err
+		}
+		message, err := config.Canonicalize(req, body)
+		if err != nil {
+			return err
+		}
+		mac := hmac.New(config.Hash, key)
+		mac.Write([]byte(message))
+		req.Header.Set(config.Header, config.Prefix+config.Encode(mac.Sum(nil)))
+		return nil
+	}
+}
+
+func CanonicalHMACRequest(req *http.Request, body []byte) (string, error) {
+	return req.Method + "\n" + req.URL.RequestURI() + "\n" + sha256Hex(body), nil
+}
@@ -0,0 +1,24 @@
+package dlutil
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAWSCanonicalQuery(t *testing.T) {
+	tests := map[string]string{
+		"a-b=1&a=2":         "a=2&a-b=1",
+		"b=2&a=z&a=y":       "a=y&a=z&b=2",
+		"key=a+b&key=a%2Fb": "key=a%20b&key=a%2Fb",
+		"x.y=1&x=1&x_y=1":   "x=1&x.y=1&x_y=1",
+	}
+	for query, want := range tests {
+		req, err := http.NewRequest(http.MethodGet, "https://example.com/?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := awsCanonicalQuery(req); got != want {
+			t.Errorf("%s: got %q, want %q", query, got, want)
+		}
+	}
+}
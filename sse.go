@@ -0,0 +1,140 @@
+package dlutil
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultSSEReconnectDelay = 3 * time.Second
+
+type Event struct {
+	ID   string
+	Type string
+	Data string
+}
+
+func DownloadSSE(url string, o ...DownloadOption) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		opts := newDownloadOptions(o)
+		opts.Cache = nil
+		opts.ParallelChunks = 0
+		opts.ChecksumHash = 0
+		opts.SignatureVerifier = nil
+
+		var reqBody []byte
+		if opts.Body != nil {
+			var err error
+			if reqBody, err = io.ReadAll(opts.Body); err != nil {
+				yield(Event{}, err)
+				return
+			}
+		}
+
+		s := &sseStream{reconnectDelay: defaultSSEReconnectDelay}
+		connected := false
+		for {
+			connOpts := opts
+			connOpts.Header = opts.Header.Clone()
+			if connOpts.Header == nil {
+				connOpts.Header = make(http.Header)
+			}
+			connOpts.Header.Set("Accept", "text/event-stream")
+			connOpts.Header.Set("Cache-Control", "no-cache")
+			if len(s.lastEventID) > 0 {
+				connOpts.Header.Set("Last-Event-ID", s.lastEventID)
+			}
+			if opts.Body != nil {
+				connOpts.Body = bytes.NewReader(reqBody)
+			}
+
+			body, resp, err := doDownload(url, &connOpts)
+			if err != nil {
+				var statusErr *BadStatusError
+				if !connected || opts.Ctx.Err() != nil || errors.As(err, &statusErr) {
+					yield(Event{}, err)
+					return
+				}
+			} else if resp.StatusCode == http.StatusNoContent {
+				body.Close()
+				return
+			} else {
+				connected = true
+				ok := s.read(body, yield)
+				body.Close()
+				if !ok {
+					return
+				}
+			}
+
+			if err := sleepContext(opts.Ctx, s.reconnectDelay); err != nil {
+				yield(Event{}, err)
+				return
+			}
+		}
+	}
+}
+
+type sseStream struct {
+	lastEventID    string
+	reconnectDelay time.Duration
+}
+
+func (s *sseStream) read(r io.Reader, yield func(Event, error) bool) bool {
+	br := bufio.NewReader(r)
+	var eventType string
+	var data strings.Builder
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && len(line) == 0 {
+			return true
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if len(line) == 0 {
+			if data.Len() > 0 {
+				event := Event{
+					ID:   s.lastEventID,
+					Type: eventType,
+					Data: strings.TrimSuffix(data.String(), "\n"),
+				}
+				if len(event.Type) == 0 {
+					event.Type = "message"
+				}
+				if !yield(event, nil) {
+					return false
+				}
+			}
+			eventType = ""
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				s.reconnectDelay = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package dlutil
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	neturl "net/url"
+	"sync"
+)
+
+type BlockedAddressError struct {
+	Host string
+	Addr netip.Addr
+}
+
+func (e BlockedAddressError) Error() string {
+	return "blocked address for host " + e.Host + ": " + e.Addr.String()
+}
+
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+func WithSSRFProtection() DownloadOption {
+	return func(do *DownloadOptions) {
+		do.SSRFProtection = true
+	}
+}
+
+func checkAddrs(host string, ips []netip.Addr) error {
+	for _, ip := range ips {
+		if isBlockedAddr(ip.Unmap()) {
+			return &BlockedAddressError{Host: host, Addr: ip.Unmap()}
+		}
+	}
+	return nil
+}
+
+func guardProxy(t *http.Transport, lookup func(ctx context.Context, host string) ([]netip.Addr, error)) func(addr string) bool {
+	if t.Proxy == nil {
+		return func(string) bool { return false }
+	}
+	var proxies sync.Map
+	proxyFunc := t.Proxy
+	t.Proxy = func(req *http.Request) (*neturl.URL, error) {
+		u, err := proxyFunc(req)
+		if err != nil || u == nil {
+			return u, err
+		}
+		host := req.URL.Hostname()
+		ips, err := lookup(req.Context(), host)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkAddrs(host, ips); err != nil {
+			return nil, err
+		}
+		proxies.Store(proxyAddr(u), struct{}{})
+		return u, nil
+	}
+	return func(addr string) bool {
+		_, ok := proxies.Load(addr)
+		return ok
+	}
+}
+
+func proxyAddr(u *neturl.URL) string {
+	port := u.Port()
+	if len(port) == 0 {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func isBlockedAddr(ip netip.Addr) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
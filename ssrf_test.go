@@ -0,0 +1,29 @@
+package dlutil
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSRFProtectionBehindProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied " + r.URL.Host))
+	}))
+	defer proxy.Close()
+
+	o := []DownloadOption{WithClient(&http.Client{Transport: &http.Transport{}}), WithProxy(proxy.URL), WithSSRFProtection()}
+	got, err := DownloadString("http://93.184.215.14/", o...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "proxied 93.184.215.14" {
+		t.Fatalf("got %q", got)
+	}
+
+	var blocked *BlockedAddressError
+	if _, err := DownloadString("http://127.0.0.1:1/", o...); !errors.As(err, &blocked) {
+		t.Fatalf("expected blocked address error, got %v", err)
+	}
+}
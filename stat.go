@@ -0,0 +1,95 @@
+package dlutil
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type RemoteFileInfo struct {
+	URL          string
+	Size         int64
+	LastModified time.Time
+	ETag         string
+	ContentType  string
+	AcceptRanges bool
+}
+
+func Stat(url string, o ...DownloadOption) (*RemoteFileInfo, error) {
+	opts := probeOptions(o, http.MethodHead)
+
+	body, resp, err := doDownload(url, &opts)
+	if err != nil {
+		return nil, err
+	}
+	body.Close()
+
+	info := &RemoteFileInfo{
+		Size:         resp.ContentLength,
+		ETag:         resp.Header.Get("ETag"),
+		AcceptRanges: strings.EqualFold(strings.TrimSpace(resp.Header.Get("Accept-Ranges")), "bytes"),
+	}
+	if resp.Request != nil {
+		info.URL = resp.Request.URL.String()
+	} else if info.URL, err = requestURL(url, &opts); err != nil {
+		return nil, err
+	}
+	info.ContentType, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = lastModified
+	}
+	return info, nil
+}
+
+func Exists(url string, o ...DownloadOption) (bool, error) {
+	_, err := Stat(url, o...)
+	var statusErr *BadStatusError
+	if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusMethodNotAllowed || statusErr.StatusCode == http.StatusNotImplemented) {
+		err = existsByRange(url, o)
+	}
+	if err == nil {
+		return true, nil
+	}
+	if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone) {
+		return false, nil
+	}
+	return false, err
+}
+
+func existsByRange(url string, o []DownloadOption) error {
+	opts := probeOptions(o, http.MethodGet)
+	opts.Header = opts.Header.Clone()
+	if opts.Header == nil {
+		opts.Header = make(http.Header)
+	}
+	opts.Header.Set("Range", "bytes=0-0")
+
+	body, _, err := doDownload(url, &opts)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+func probeOptions(o []DownloadOption, method string) DownloadOptions {
+	opts := newDownloadOptions(o)
+	opts.Method = method
+	opts.Body = nil
+	opts.BodyContentType = ""
+	opts.Cache = nil
+	opts.ParallelChunks = 0
+	opts.MaxSize = 0
+	opts.MinSpeed = 0
+	opts.AutoDecompress = false
+	opts.MaxDecompressedSize = 0
+	opts.MaxCompressionRatio = 0
+	opts.ChecksumHash = 0
+	opts.Checksum = nil
+	opts.ChecksumURL = ""
+	opts.SignatureVerifier = nil
+	opts.SignatureURL = ""
+	opts.ArchiveEntryFunc = nil
+	return opts
+}
@@ -0,0 +1,100 @@
+package dlutil
+
+import (
+	"crypto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatWithoutRequest(t *testing.T) {
+	stub := func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Header:        http.Header{"Content-Length": {"42"}},
+				Body:          io.NopCloser(strings.NewReader("")),
+				ContentLength: 42,
+			}, nil
+		}
+	}
+	info, err := Stat("http://example.com/file", WithMiddleware(stub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.URL != "http://example.com/file" || info.Size != 42 {
+		t.Fatalf("got %+v", info)
+	}
+}
+
+func TestStatExpandsURL(t *testing.T) {
+	stub := func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}, nil
+		}
+	}
+	info, err := Stat("/files/{id}", WithBaseURL("http://example.com/api/"), PathParam("id", "7"), WithMiddleware(stub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.URL != "http://example.com/api/files/7" {
+		t.Fatalf("got %q", info.URL)
+	}
+}
+
+func TestStatIgnoresBodyChecks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", "1000")
+		if r.Method == http.MethodGet {
+			w.Write(make([]byte, 1000))
+		}
+	}))
+	defer srv.Close()
+
+	info, err := Stat(srv.URL, WithMaxSize(10), WithAutoDecompress(), WithChecksum(crypto.SHA256, make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != 1000 {
+		t.Fatalf("got %+v", info)
+	}
+	ok, err := Exists(srv.URL, WithMaxSize(10), WithAutoDecompress())
+	if err != nil || !ok {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+}
+
+func TestExists(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/head-only-get":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if r.Header.Get("Range") != "bytes=0-0" {
+				t.Errorf("unexpected range %q", r.Header.Get("Range"))
+			}
+			w.Write([]byte("x"))
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	for path, want := range map[string]bool{"/": true, "/head-only-get": true, "/gone": false, "/missing": false} {
+		if ok, err := Exists(srv.URL + path); err != nil || ok != want {
+			t.Errorf("%s: got %v, %v", path, ok, err)
+		}
+	}
+	if _, err := Exists(srv.URL + "/broken"); err == nil {
+		t.Error("expected error for 500")
+	}
+}
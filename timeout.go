@@ -0,0 +1,72 @@
+package dlutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var ErrAttemptTimeout = errors.New("attempt timed out")
+
+func WithTimeout(total time.Duration) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.Timeout = total
+	}
+}
+
+func WithAttemptTimeout(perTry time.Duration) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.AttemptTimeout = perTry
+	}
+}
+
+func WithResponseHeaderTimeout(d time.Duration) DownloadOption {
+	return withTransportOption("response-header-timeout "+d.String(), func(t *http.Transport) error {
+		t.ResponseHeaderTimeout = d
+		return nil
+	})
+}
+
+func doDownloadWithTimeout(url string, opts *DownloadOptions) (io.ReadCloser, *http.Response, error) {
+	ctx, cancel := context.WithTimeout(opts.Ctx, opts.Timeout)
+	opts.Ctx = ctx
+	opts.Timeout = 0
+
+	body, resp, err := doDownload(url, opts)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return closeWith(body, cancel), resp, nil
+}
+
+func sendAttempt(req *http.Request, opts *DownloadOptions) (*http.Response, error) {
+	if opts.AttemptTimeout <= 0 {
+		return sendHedged(req, opts)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(opts.AttemptTimeout, func() {
+		timedOut.Store(true)
+		cancel()
+	})
+	resp, err := sendHedged(req.WithContext(ctx), opts)
+	if err != nil {
+		cancel()
+		if timedOut.Load() {
+			return nil, ErrAttemptTimeout
+		}
+		return nil, err
+	}
+	if !timer.Stop() {
+		resp.Body.Close()
+		cancel()
+		return nil, ErrAttemptTimeout
+	}
+	resp.Body = closeWith(resp.Body, cancel)
+	return resp, nil
+}
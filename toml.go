@@ -0,0 +1,20 @@
+package dlutil
+
+import (
+	"github.com/BurntSushi/toml"
+)
+
+func DownloadTOML[T any](url string, o ...DownloadOption) (*T, error) {
+	body, err := Download(url, append(o, WithAcceptContentType("application/toml, text/plain"))...)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	decoder := toml.NewDecoder(body)
+	result := new(T)
+	if _, err := decoder.Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,359 @@
+package dlutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTransportNotCloneable = errors.New("client transport is not an *http.Transport")
+	ErrCertPinMismatch       = errors.New("no certificate matches the pinned public keys")
+)
+
+const maxCachedTransports = 64
+
+type transportOption struct {
+	key   string
+	apply func(t *http.Transport) error
+}
+
+type TransportTuning struct {
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+}
+
+type roundTripper interface {
+	http.RoundTripper
+	CloseIdleConnections()
+}
+
+type transportKey struct {
+	base      *http.Transport
+	options   string
+	tuning    TransportTuning
+	tuned     bool
+	http3     bool
+	resolver  Resolver
+	dnsCache  *DNSCache
+	dnsTTL    time.Duration
+	overrides string
+	ssrf      bool
+}
+
+type cachedTransport struct {
+	rt      roundTripper
+	options []*transportOption
+}
+
+var (
+	transportsMu sync.Mutex
+	transports   = make(map[transportKey]cachedTransport)
+)
+
+func WithTransportOption(apply func(t *http.Transport) error) DownloadOption {
+	return withTransportOption("", apply)
+}
+
+func withTransportOption(key string, apply func(t *http.Transport) error) DownloadOption {
+	option := &transportOption{key: key, apply: apply}
+	return func(do *DownloadOptions) {
+		do.TransportOptions = append(do.TransportOptions, option)
+	}
+}
+
+func WithClientOption(option func(c *http.Client)) DownloadOption {
+	return func(do *DownloadOptions) {
+		do.ClientOptions = append(do.ClientOptions, option)
+	}
+}
+
+func WithClientCert(cert tls.Certificate) DownloadOption {
+	h := sha256.New()
+	for _, der := range cert.Certificate {
+		h.Write(der)
+	}
+	return withTransportOption("client-cert "+hex.EncodeToString(h.Sum(nil)), func(t *http.Transport) error {
+		config := tlsClientConfig(t)
+		config.Certificates = append(slices.Clip(config.Certificates), cert)
+		return nil
+	})
+}
+
+func WithRootCAs(pool *x509.CertPool) DownloadOption {
+	return withTransportOption(fmt.Sprintf("root-cas %p", pool), func(t *http.Transport) error {
+		tlsClientConfig(t).RootCAs = pool
+		return nil
+	})
+}
+
+func WithCAFile(path string) DownloadOption {
+	return withTransportOption("ca-file "+path, func(t *http.Transport) error {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no certificates found in CA file: " + path)
+		}
+		tlsClientConfig(t).RootCAs = pool
+		return nil
+	})
+}
+
+func WithInsecureTLS() DownloadOption {
+	return withTransportOption("insecure-tls", func(t *http.Transport) error {
+		tlsClientConfig(t).InsecureSkipVerify = true
+		return nil
+	})
+}
+
+func WithPinnedCert(spkiSHA256 ...string) DownloadOption {
+	return withTransportOption("pinned-cert "+strings.Join(spkiSHA256, ","), func(t *http.Transport) error {
+		pins := make(map[[sha256.Size]byte]bool, len(spkiSHA256))
+		for _, pin := range spkiSHA256 {
+			sum, err := decodePin(pin)
+			if err != nil {
+				return err
+			}
+			pins[sum] = true
+		}
+		config := tlsClientConfig(t)
+		verify := config.VerifyConnection
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(cs); err != nil {
+					return err
+				}
+			}
+			if config.InsecureSkipVerify {
+				if len(cs.PeerCertificates) > 0 && pins[sha256.Sum256(cs.PeerCertificates[0].RawSubjectPublicKeyInfo)] {
+					return nil
+				}
+				return ErrCertPinMismatch
+			}
+			for _, chain := range cs.VerifiedChains {
+				for _, cert := range chain {
+					if pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+						return nil
+					}
+				}
+			}
+			return ErrCertPinMismatch
+		}
+		return nil
+	})
+}
+
+func decodePin(pin string) (sum [sha256.Size]byte, err error) {
+	pin = strings.TrimPrefix(pin, "sha256/")
+	decoded, err := base64.StdEncoding.DecodeString(pin)
+	if err != nil || len(decoded) != sha256.Size {
+		decoded, err = hex.DecodeString(pin)
+	}
+	if err != nil || len(decoded) != sha256.Size {
+		return sum, errors.New("invalid SPKI SHA-256 pin: " + pin)
+	}
+	copy(sum[:], decoded)
+	return sum, nil
+}
+
+func WithTransportTuning(maxIdleConnsPerHost, maxConnsPerHost int, idleTimeout time.Duration) DownloadOption {
+	tuning := &TransportTuning{
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		MaxConnsPerHost:     maxConnsPerHost,
+		IdleConnTimeout:     idleTimeout,
+	}
+	return func(do *DownloadOptions) {
+		do.TransportTuning = tuning
+	}
+}
+
+func (tuning *TransportTuning) apply(t *http.Transport) {
+	t.MaxIdleConnsPerHost = tuning.MaxIdleConnsPerHost
+	t.MaxIdleConns = max(t.MaxIdleConns, tuning.MaxIdleConnsPerHost)
+	t.MaxConnsPerHost = tuning.MaxConnsPerHost
+	t.IdleConnTimeout = tuning.IdleConnTimeout
+}
+
+func tlsClientConfig(t *http.Transport) *tls.Config {
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	return t.TLSClientConfig
+}
+
+func needsTransport(opts *DownloadOptions) bool {
+	return len(opts.TransportOptions) > 0 || opts.TransportTuning != nil || needsResolvingDialer(opts) || opts.HTTP3
+}
+
+func deriveTransport(opts *DownloadOptions) (*http.Transport, error) {
+	base := opts.Client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return nil, ErrTransportNotCloneable
+	}
+	transport = transport.Clone()
+	for _, option := range opts.TransportOptions {
+		if err := option.apply(transport); err != nil {
+			return nil, err
+		}
+	}
+	if opts.TransportTuning != nil {
+		opts.TransportTuning.apply(transport)
+	}
+	if needsResolvingDialer(opts) {
+		if err := applyResolvingDialer(transport, opts); err != nil {
+			return nil, err
+		}
+	}
+	return transport, nil
+}
+
+func deriveRoundTripper(opts *DownloadOptions) (roundTripper, bool, error) {
+	key, ok := newTransportKey(opts)
+	if ok {
+		transportsMu.Lock()
+		cached, found := transports[key]
+		transportsMu.Unlock()
+		if found {
+			return cached.rt, true, nil
+		}
+	}
+	transport, err := deriveTransport(opts)
+	if err != nil {
+		return nil, false, err
+	}
+	var rt roundTripper = transport
+	if opts.HTTP3 && !opts.SOCKS5 && len(opts.UnixSocket) == 0 {
+		rt = newHTTP3Transport(transport, opts)
+	}
+	if !ok {
+		return rt, false, nil
+	}
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if cached, found := transports[key]; found {
+		rt.CloseIdleConnections()
+		return cached.rt, true, nil
+	}
+	if len(transports) >= maxCachedTransports {
+		for _, cached := range transports {
+			cached.rt.CloseIdleConnections()
+		}
+		clear(transports)
+	}
+	transports[key] = cachedTransport{rt: rt, options: opts.TransportOptions}
+	return rt, true, nil
+}
+
+func newTransportKey(opts *DownloadOptions) (transportKey, bool) {
+	base := opts.Client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport, ok := base.(*http.Transport)
+	if !ok || opts.Resolver != nil && !reflect.ValueOf(opts.Resolver).Comparable() {
+		return transportKey{}, false
+	}
+	key := transportKey{
+		base:     transport,
+		http3:    opts.HTTP3,
+		resolver: opts.Resolver,
+		dnsCache: opts.DNSCache,
+		dnsTTL:   opts.DNSCacheTTL,
+		ssrf:     opts.SSRFProtection,
+	}
+	var sb strings.Builder
+	for _, option := range opts.TransportOptions {
+		if len(option.key) > 0 {
+			sb.WriteString(option.key)
+		} else {
+			fmt.Fprintf(&sb, "%p", option)
+		}
+		sb.WriteByte(0)
+	}
+	key.options = sb.String()
+	if opts.TransportTuning != nil {
+		key.tuning, key.tuned = *opts.TransportTuning, true
+	}
+	if len(opts.ResolveOverrides) > 0 {
+		hosts := slices.Sorted(maps.Keys(opts.ResolveOverrides))
+		sb.Reset()
+		for _, host := range hosts {
+			sb.WriteString(host + "=" + opts.ResolveOverrides[host] + ",")
+		}
+		key.overrides = sb.String()
+	}
+	return key, true
+}
+
+func deriveClient(opts *DownloadOptions) {
+	client := *opts.Client
+	for _, option := range opts.ClientOptions {
+		option(&client)
+	}
+	opts.Client = &client
+	opts.ClientOptions = nil
+}
+
+func doDownloadWithTransport(url string, opts *DownloadOptions) (io.ReadCloser, *http.Response, error) {
+	rt, cached, err := deriveRoundTripper(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := *opts.Client
+	client.Transport = rt
+	opts.Client = &client
+	opts.TransportOptions = nil
+	opts.TransportTuning = nil
+	opts.HTTP3 = false
+	opts.Resolver = nil
+	opts.DNSCache = nil
+	opts.SSRFProtection = false
+	opts.ResolveOverrides = nil
+
+	body, resp, err := doDownload(url, opts)
+	if cached {
+		return body, resp, err
+	}
+	if err != nil {
+		rt.CloseIdleConnections()
+		return nil, nil, err
+	}
+	return closeWith(body, rt.CloseIdleConnections), resp, nil
+}
+
+func WithUnixSocket(path string) DownloadOption {
+	return WithOptions(func(do *DownloadOptions) {
+		do.UnixSocket = path
+	}, withTransportOption("unix "+path, func(t *http.Transport) error {
+		dialer := &net.Dialer{}
+		t.Proxy = nil
+		t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		return nil
+	}))
+}
@@ -0,0 +1,207 @@
+package dlutil
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	der  []byte
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, der: der, key: key}
+}
+
+func (c *testCert) pin() string {
+	sum := sha256.Sum256(c.cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func newPinningServer(t *testing.T, chain [][]byte, key *ecdsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: chain, PrivateKey: key}}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPinnedCert(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	leaf := newTestCert(t, "leaf", ca)
+	pinned := newTestCert(t, "pinned", nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &http.Client{Transport: &http.Transport{}}
+
+	t.Run("leaf pin", func(t *testing.T) {
+		srv := newPinningServer(t, [][]byte{leaf.der}, leaf.key)
+		_, err := DownloadBytes(srv.URL, WithClient(client), WithRootCAs(roots), WithPinnedCert(leaf.pin()))
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("appended pinned cert", func(t *testing.T) {
+		srv := newPinningServer(t, [][]byte{leaf.der, pinned.der}, leaf.key)
+		_, err := DownloadBytes(srv.URL, WithClient(client), WithRootCAs(roots), WithPinnedCert(pinned.pin()))
+		if !errors.Is(err, ErrCertPinMismatch) {
+			t.Fatalf("expected pin mismatch, got %v", err)
+		}
+	})
+
+	t.Run("insecure checks leaf only", func(t *testing.T) {
+		srv := newPinningServer(t, [][]byte{leaf.der, pinned.der}, leaf.key)
+		_, err := DownloadBytes(srv.URL, WithClient(client), WithInsecureTLS(), WithPinnedCert(pinned.pin()))
+		if !errors.Is(err, ErrCertPinMismatch) {
+			t.Fatalf("expected pin mismatch, got %v", err)
+		}
+		if _, err := DownloadBytes(srv.URL, WithClient(client), WithInsecureTLS(), WithPinnedCert(leaf.pin())); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestTransportTuningReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	orders := map[string]func(client *http.Client) []DownloadOption{
+		"tuning first": func(client *http.Client) []DownloadOption {
+			return []DownloadOption{WithTransportTuning(4, 4, time.Minute), WithClient(client), WithRootCAs(nil)}
+		},
+		"client first": func(client *http.Client) []DownloadOption {
+			return []DownloadOption{WithClient(client), WithRootCAs(nil), WithTransportTuning(4, 4, time.Minute)}
+		},
+	}
+	for name, o := range orders {
+		t.Run(name, func(t *testing.T) {
+			conns.Store(0)
+			d := NewDownloader(o(&http.Client{Transport: &http.Transport{}})...)
+			for range 3 {
+				if _, err := d.GetBytes(srv.URL); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if n := conns.Load(); n != 1 {
+				t.Fatalf("expected 1 connection, got %d", n)
+			}
+		})
+	}
+}
+
+func TestTransportCacheKeysOnOptionValues(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("other"))
+	}))
+	defer other.Close()
+
+	count := func() int {
+		transportsMu.Lock()
+		defer transportsMu.Unlock()
+		return len(transports)
+	}
+	client := &http.Client{Transport: &http.Transport{}}
+	before := count()
+	for range 3 {
+		got, err := DownloadString("http://example.com/", WithClient(client), WithProxy(proxy.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "proxied" {
+			t.Fatalf("got %q", got)
+		}
+	}
+	if n := count() - before; n != 1 {
+		t.Fatalf("expected 1 cached transport, got %d", n)
+	}
+	got, err := DownloadString("http://example.com/", WithClient(client), WithProxy(other.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "other" || count()-before != 2 {
+		t.Fatalf("got %q with %d cached transports", got, count()-before)
+	}
+}
+
+func TestClientCertDoesNotShareCertificates(t *testing.T) {
+	base := &http.Transport{TLSClientConfig: &tls.Config{Certificates: make([]tls.Certificate, 1, 4)}}
+	var derived []*http.Transport
+	for _, name := range []string{"a", "b"} {
+		cert := newTestCert(t, name, nil)
+		opts := newDownloadOptions([]DownloadOption{
+			WithClient(&http.Client{Transport: base}),
+			WithClientCert(tls.Certificate{Certificate: [][]byte{cert.der}, PrivateKey: cert.key}),
+		})
+		transport, err := deriveTransport(&opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		derived = append(derived, transport)
+	}
+	a, b := derived[0].TLSClientConfig.Certificates, derived[1].TLSClientConfig.Certificates
+	if len(a) != 2 || len(b) != 2 || bytes.Equal(a[1].Certificate[0], b[1].Certificate[0]) {
+		t.Fatal("derived transports share client certificates")
+	}
+}